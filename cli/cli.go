@@ -0,0 +1,349 @@
+// Package cli runs the registered filters on image files without a window.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"photoshop/filters"
+	"photoshop/imageio"
+	"photoshop/lut"
+)
+
+const usage = `usage:
+  photoshop apply --filter NAME [--quality Q] [--PARAM VALUE ...] INPUT OUTPUT
+  photoshop lut [--title T] OUTPUT.cube NAME [--PARAM VALUE ...] [+ NAME [--PARAM VALUE ...] ...]
+  photoshop palette [--colors N] [--method M] [--dither D] INPUT OUTPUT.gpl
+  photoshop list
+
+The output format is chosen by the OUTPUT extension (.png, .jpg, .gif, .bmp);
+--quality sets the JPEG quality (1-100).
+"lut" chains colour mappings such as gamma, curves or apply-lut, separated
+by "+", and writes them as one .cube lookup table.
+"palette" writes the colours the palette filter picks as a GIMP palette;
+apply the palette filter with a .png or .gif OUTPUT for an indexed image.
+Run "photoshop list" to see every filter and its parameters.
+`
+
+// IsCommand reports whether args start with a command handled by Run, so
+// that the GUI can fall through to its window otherwise.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "apply", "lut", "palette", "list", "help", "-h", "--help":
+		return true
+	}
+	return false
+}
+
+// Run executes the command in args and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "apply":
+		err = runApply(args[1:], stderr)
+	case "lut":
+		err = runLUT(args[1:], stderr)
+	case "palette":
+		err = runPalette(args[1:], stderr)
+	case "list":
+		listFilters(stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "photoshop:", err)
+		return 1
+	}
+	return 0
+}
+
+// filterName finds the value of --filter before the full flag set is known.
+func filterName(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "filter" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func runApply(args []string, stderr io.Writer) error {
+	name := filterName(args)
+	if name == "" {
+		return fmt.Errorf("apply: --filter is required (one of %s)", strings.Join(filters.Names(), ", "))
+	}
+	f, ok := filters.Lookup(name)
+	if !ok {
+		return fmt.Errorf("apply: unknown filter %q (one of %s)", name, strings.Join(filters.Names(), ", "))
+	}
+
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.String("filter", "", "filter to apply")
+	quality := flags.Int("quality", 0, "JPEG quality (1-100)")
+
+	readParams := paramFlags(flags, f)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("apply: expected INPUT and OUTPUT paths")
+	}
+	// Reject the output format before a slow filter runs for nothing.
+	if _, err := imageio.FormatFromPath(flags.Arg(1)); err != nil {
+		return err
+	}
+
+	params, err := readParams()
+	if err != nil {
+		return fmt.Errorf("apply: %w", err)
+	}
+
+	src, err := imageio.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := f.Apply(ctx, src, params)
+	if err != nil {
+		return err
+	}
+
+	if reporter, ok := f.(filters.Reporter); ok {
+		report, err := reporter.Report(ctx, src, params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stderr, report)
+	}
+
+	return imageio.Save(flags.Arg(1), result, imageio.Options{Quality: *quality})
+}
+
+// paramFlags defines a flag for every parameter of f; the returned
+// function collects the values that were set once flags are parsed.
+func paramFlags(flags *flag.FlagSet, f filters.Filter) func() (filters.Params, error) {
+	values := make(map[string]*string, len(f.Params()))
+	for _, param := range f.Params() {
+		values[param.Name] = flags.String(param.Name, "", paramUsage(param))
+	}
+
+	return func() (filters.Params, error) {
+		params := filters.Params{}
+		for _, param := range f.Params() {
+			text := *values[param.Name]
+			if text == "" {
+				continue
+			}
+			value, err := parseParam(param, text)
+			if err != nil {
+				return nil, fmt.Errorf("--%s: %w", param.Name, err)
+			}
+			params[param.Name] = value
+		}
+		return params, nil
+	}
+}
+
+// runLUT composes the lookup tables of the filters in args and writes
+// them as a .cube file.
+func runLUT(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("lut", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	title := flags.String("title", "", "TITLE of the .cube file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New("lut: expected OUTPUT.cube and at least one filter")
+	}
+
+	var steps []filters.ChainStep
+	for _, group := range splitSteps(flags.Args()[1:]) {
+		if len(group) == 0 {
+			return errors.New("lut: empty step between \"+\"")
+		}
+		f, ok := filters.Lookup(group[0])
+		if !ok {
+			return fmt.Errorf("lut: unknown filter %q (one of %s)", group[0], strings.Join(filters.Names(), ", "))
+		}
+
+		stepFlags := flag.NewFlagSet(f.Name(), flag.ContinueOnError)
+		stepFlags.SetOutput(stderr)
+		readParams := paramFlags(stepFlags, f)
+		if err := stepFlags.Parse(group[1:]); err != nil {
+			return err
+		}
+		if stepFlags.NArg() > 0 {
+			return fmt.Errorf("lut: %s: unexpected argument %q", f.Name(), stepFlags.Arg(0))
+		}
+
+		params, err := readParams()
+		if err != nil {
+			return fmt.Errorf("lut: %s: %w", f.Name(), err)
+		}
+		steps = append(steps, filters.ChainStep{Filter: f.Name(), Params: params})
+	}
+
+	chain, err := filters.ChainLUT(steps)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := lut.WriteCube(out, &lut.Cube{Title: *title, LUT: chain}); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runPalette reduces INPUT with the palette filter and writes the palette
+// it picked as a .gpl file.
+func runPalette(args []string, stderr io.Writer) error {
+	f, ok := filters.Lookup("palette")
+	if !ok {
+		return errors.New("palette: the palette filter is not registered")
+	}
+
+	flags := flag.NewFlagSet("palette", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	readParams := paramFlags(flags, f)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("palette: expected INPUT and OUTPUT.gpl paths")
+	}
+
+	params, err := readParams()
+	if err != nil {
+		return fmt.Errorf("palette: %w", err)
+	}
+
+	src, err := imageio.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := f.Apply(ctx, src, params)
+	if err != nil {
+		return err
+	}
+	paletted, ok := result.(*image.Paletted)
+	if !ok {
+		return fmt.Errorf("palette: %s did not produce an indexed image", f.Name())
+	}
+
+	out, err := os.Create(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(flags.Arg(0)), filepath.Ext(flags.Arg(0)))
+	if err := imageio.WriteGPL(out, name, paletted.Palette); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// splitSteps cuts args at every "+".
+func splitSteps(args []string) [][]string {
+	var groups [][]string
+	start := 0
+	for i, arg := range args {
+		if arg == "+" {
+			groups = append(groups, args[start:i])
+			start = i + 1
+		}
+	}
+	return append(groups, args[start:])
+}
+
+func parseParam(param filters.Param, text string) (any, error) {
+	switch param.Kind {
+	case filters.ParamInt:
+		return strconv.Atoi(text)
+	case filters.ParamFloat:
+		value, ok := filters.ParseNumber(text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", text)
+		}
+		return value, nil
+	case filters.ParamChoice:
+		for _, choice := range param.Choices {
+			if strings.EqualFold(choice, text) {
+				return choice, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(param.Choices, ", "))
+	}
+	return text, nil
+}
+
+func paramUsage(param filters.Param) string {
+	text := param.Label
+	if param.Kind == filters.ParamChoice {
+		text += " (" + strings.Join(param.Choices, "|") + ")"
+	}
+	if param.Default != nil {
+		text += fmt.Sprintf(", default %v", param.Default)
+	} else {
+		text += ", required"
+	}
+	return text
+}
+
+func listFilters(w io.Writer) {
+	for _, category := range filters.Categories() {
+		fmt.Fprintf(w, "%s:\n", category)
+		for _, f := range filters.ByCategory(category) {
+			fmt.Fprintf(w, "  %-18s %s\n", f.Name(), f.Title())
+			for _, param := range f.Params() {
+				fmt.Fprintf(w, "      --%-12s %s\n", param.Name, paramUsage(param))
+			}
+		}
+	}
+}
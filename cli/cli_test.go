@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photoshop/filters"
+	"photoshop/imageio"
+)
+
+func writeTestImage(t *testing.T) string {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 32), G: uint8(y * 32), B: 128, A: 255})
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "in.png")
+	if err := imageio.Save(path, img, imageio.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	in := writeTestImage(t)
+	out := filepath.Join(filepath.Dir(in), "out.png")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no arguments", nil, 2, "usage:"},
+		{"unknown command", []string{"resize"}, 1, `unknown command "resize"`},
+		{"no filter", []string{"apply", in, out}, 1, "--filter is required"},
+		{"unknown filter", []string{"apply", "--filter", "sharpen-more", in, out}, 1, `unknown filter "sharpen-more"`},
+		{"missing paths", []string{"apply", "--filter", "gauss", "--size", "5", in}, 1, "expected INPUT and OUTPUT"},
+		{"output extension", []string{"apply", "--filter", "gauss", "--size", "5", "missing.png", "out.tif"}, 1, "unsupported file extension"},
+		{"missing parameter", []string{"apply", "--filter", "gauss", in, out}, 1, `missing parameter "size"`},
+		{"bad int", []string{"apply", "--filter", "gauss", "--size", "five", in, out}, 1, "--size"},
+		{"bad float", []string{"apply", "--filter", "gamma", "--gamma", "1/0", in, out}, 1, "invalid number"},
+		{"bad choice", []string{"apply", "--filter", "gauss", "--size", "5", "--border", "mirror", in, out}, 1, "must be one of"},
+		{"above max", []string{"apply", "--filter", "gauss", "--size", "121", in, out}, 1, "between 3 and 99"},
+		{"below min", []string{"apply", "--filter", "brightness", "--value", "-1", in, out}, 1, "between 0 and 255"},
+		{"apply", []string{"apply", "--filter", "gauss", "--size", "5", "--border", "reflect", in, out}, 0, ""},
+		{"fraction", []string{"apply", "--filter", "gamma", "--gamma", "1/2", in, out}, 0, ""},
+		{"lut out of range", []string{"lut", filepath.Join(filepath.Dir(in), "out.cube"), "gamma", "--gamma", "300", "+", "negative"}, 1, "between 0 and 255"},
+	}
+
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		code := Run(tc.args, &stdout, &stderr)
+		if code != tc.code {
+			t.Errorf("%s: Run returned %d, want %d (stderr %q)", tc.name, code, tc.code, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), tc.stderr) {
+			t.Errorf("%s: stderr %q does not contain %q", tc.name, stderr.String(), tc.stderr)
+		}
+	}
+
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("no output written: %v", err)
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	intParam := filters.Param{Name: "size", Kind: filters.ParamInt, Min: 3, Max: 99}
+	floatParam := filters.Param{Name: "gamma", Kind: filters.ParamFloat, Min: 0, Max: 255}
+	choiceParam := filters.Param{Name: "border", Kind: filters.ParamChoice, Choices: []string{"Clamp", "Reflect"}}
+	textParam := filters.Param{Name: "file", Kind: filters.ParamText}
+
+	tests := []struct {
+		param filters.Param
+		text  string
+		want  any
+		ok    bool
+	}{
+		{intParam, "5", 5, true},
+		{intParam, "5.5", nil, false},
+		{intParam, "", nil, false},
+		{floatParam, "0.25", 0.25, true},
+		{floatParam, "1/2", 0.5, true},
+		{floatParam, " 3 ", 3., true},
+		{floatParam, "1/0", nil, false},
+		{floatParam, "half", nil, false},
+		{choiceParam, "reflect", "Reflect", true},
+		{choiceParam, "CLAMP", "Clamp", true},
+		{choiceParam, "wrap", nil, false},
+		{textParam, "a.cube", "a.cube", true},
+	}
+
+	for _, tc := range tests {
+		got, err := parseParam(tc.param, tc.text)
+		if (err == nil) != tc.ok {
+			t.Errorf("parseParam(%s, %q) error = %v, want ok %v", tc.param.Name, tc.text, err, tc.ok)
+			continue
+		}
+		if tc.ok && got != tc.want {
+			t.Errorf("parseParam(%s, %q) = %v, want %v", tc.param.Name, tc.text, got, tc.want)
+		}
+	}
+}
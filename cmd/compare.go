@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"math"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+type viewMode int
+
+const (
+	viewSingle viewMode = iota
+	viewSplit
+	viewSideBySide
+)
+
+var viewModeNames = []string{"Single", "Split", "Side by side"}
+
+// NewImageArea shows the image with a toolbar switching between the plain
+// view, a before/after split with a draggable divider and the original
+// next to the result, and setting the zoom. All views share one viewport,
+// so zoom and pan stay in sync; the mouse wheel zooms around the cursor
+// and dragging pans. A status bar below inspects the pixel under the mouse.
+func NewImageArea(ed *editor) fyne.CanvasObject {
+	vp := newViewport(func() image.Rectangle { return ed.frame })
+
+	after := func() (image.Image, image.Rectangle) {
+		return ed.img.Image, ed.frame
+	}
+	before := func() (image.Image, image.Rectangle) {
+		if ed.origImg.Image == nil {
+			return nil, image.Rectangle{}
+		}
+		return ed.origImg.Image, ed.origImg.Image.Bounds()
+	}
+
+	views := map[viewMode]fyne.CanvasObject{
+		viewSingle:     newImageView(vp, nil, after),
+		viewSplit:      newImageView(vp, before, after),
+		viewSideBySide: container.NewGridWithColumns(2, newImageView(vp, nil, before), newImageView(vp, nil, after)),
+	}
+
+	ed.onDisplay(vp.refresh)
+
+	area := container.NewStack(views[viewSingle])
+
+	modes := widget.NewRadioGroup(viewModeNames, func(selected string) {
+		for mode, name := range viewModeNames {
+			if name == selected {
+				area.Objects = []fyne.CanvasObject{views[viewMode(mode)]}
+				area.Refresh()
+			}
+		}
+	})
+	modes.Horizontal = true
+	modes.Required = true
+	modes.SetSelected(viewModeNames[viewSingle])
+
+	zoomLabel := widget.NewLabel("")
+	vp.onZoom = func(zoom float32) {
+		zoomLabel.SetText(strconv.Itoa(int(math.Round(float64(zoom)*100))) + "%")
+	}
+
+	gridCheck := widget.NewCheck("Pixel grid", vp.setGrid)
+
+	toolbar := container.NewHBox(
+		modes,
+		widget.NewSeparator(),
+		widget.NewButton("Fit", vp.setFit),
+		widget.NewButton("100%", func() { vp.setZoom(1) }),
+		widget.NewButton("200%", func() { vp.setZoom(2) }),
+		zoomLabel,
+		widget.NewSeparator(),
+		gridCheck,
+	)
+
+	return container.NewBorder(toolbar, newInspector(ed, vp, after), nil, nil, area)
+}
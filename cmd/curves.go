@@ -0,0 +1,270 @@
+package main
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+)
+
+const (
+	// pointGrab is how close, in pixels, a click has to be to pick up a
+	// control point.
+	pointGrab = 8
+	pointSize = 3
+)
+
+var (
+	curveChannelNames = []string{"RGB", "Red", "Green", "Blue"}
+	// curveChannels is the histogram shown behind each curve.
+	curveChannels  = []filters.Channel{filters.ChannelLuma, filters.ChannelRed, filters.ChannelGreen, filters.ChannelBlue}
+	curveGridColor = [3]uint8{80, 80, 80}
+)
+
+// curveEditor draws the curve of one channel over that channel's histogram
+// and lets the user add (click), move (drag) and remove (right click)
+// control points.
+type curveEditor struct {
+	widget.BaseWidget
+
+	// curves are indexed like filters.CurveParamNames.
+	curves  [4]filters.Curve
+	channel int
+	// dragging is the index of the point being moved, or -1.
+	dragging int
+
+	background *histogramView
+	raster     *canvas.Raster
+	onChanged  func()
+}
+
+func newCurveEditor(histograms filters.ChannelHistograms) *curveEditor {
+	e := &curveEditor{dragging: -1, background: newHistogramView(histograms)}
+	for i := range e.curves {
+		e.curves[i] = filters.IdentityCurve()
+	}
+	e.raster = canvas.NewRaster(e.generate)
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *curveEditor) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(e.raster)
+}
+
+func (e *curveEditor) MinSize() fyne.Size {
+	return fyne.NewSize(320, 320)
+}
+
+func (e *curveEditor) setChannel(channel int) {
+	e.channel = channel
+	e.background.visible = [filters.ChannelAlpha + 1]bool{}
+	e.background.visible[curveChannels[channel]] = true
+	e.raster.Refresh()
+}
+
+func (e *curveEditor) curve() filters.Curve {
+	return e.curves[e.channel]
+}
+
+func (e *curveEditor) setCurve(c filters.Curve) {
+	e.curves[e.channel] = c
+	e.raster.Refresh()
+	if e.onChanged != nil {
+		e.onChanged()
+	}
+}
+
+// params encodes the curves as the parameters of the curves filter.
+func (e *curveEditor) params() filters.Params {
+	params := filters.Params{}
+	for i, name := range filters.CurveParamNames {
+		params[name] = e.curves[i].String()
+	}
+	return params
+}
+
+// level converts a position in the widget to curve coordinates.
+func (e *curveEditor) level(pos fyne.Position) filters.CurvePoint {
+	size := e.Size()
+	x := math.Round(float64(pos.X/size.Width) * 255)
+	y := math.Round(float64(1-pos.Y/size.Height) * 255)
+	return filters.CurvePoint{X: min(max(x, 0), 255), Y: min(max(y, 0), 255)}
+}
+
+// position is the inverse of level.
+func (e *curveEditor) position(pt filters.CurvePoint) fyne.Position {
+	size := e.Size()
+	return fyne.NewPos(float32(pt.X/255)*size.Width, float32(1-pt.Y/255)*size.Height)
+}
+
+// nearest returns the control point within pointGrab of pos, or -1.
+func (e *curveEditor) nearest(pos fyne.Position) int {
+	best, bestDistance := -1, float32(pointGrab)
+	for i, pt := range e.curve() {
+		p := e.position(pt)
+		if d := float32(math.Hypot(float64(p.X-pos.X), float64(p.Y-pos.Y))); d <= bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+func (e *curveEditor) Tapped(event *fyne.PointEvent) {
+	if e.nearest(event.Position) >= 0 {
+		return
+	}
+
+	pt := e.level(event.Position)
+	c := slices.Clone(e.curve())
+	i, found := slices.BinarySearchFunc(c, pt.X, func(p filters.CurvePoint, x float64) int {
+		switch {
+		case p.X < x:
+			return -1
+		case p.X > x:
+			return 1
+		}
+		return 0
+	})
+	if found {
+		c[i] = pt
+	} else {
+		c = slices.Insert(c, i, pt)
+	}
+	e.setCurve(c)
+}
+
+func (e *curveEditor) TappedSecondary(event *fyne.PointEvent) {
+	i := e.nearest(event.Position)
+	if i < 0 || len(e.curve()) <= 2 {
+		return
+	}
+	e.setCurve(slices.Delete(slices.Clone(e.curve()), i, i+1))
+}
+
+func (e *curveEditor) Dragged(event *fyne.DragEvent) {
+	if e.dragging < 0 {
+		e.dragging = e.nearest(event.Position.Subtract(event.Dragged))
+		if e.dragging < 0 {
+			return
+		}
+	}
+
+	c := slices.Clone(e.curve())
+	pt := e.level(event.Position)
+
+	// A point cannot pass its neighbours, so the inputs stay increasing.
+	lo, hi := 0., 255.
+	if e.dragging > 0 {
+		lo = c[e.dragging-1].X + 1
+	}
+	if e.dragging < len(c)-1 {
+		hi = c[e.dragging+1].X - 1
+	}
+	pt.X = min(max(pt.X, lo), hi)
+
+	c[e.dragging] = pt
+	e.setCurve(c)
+}
+
+func (e *curveEditor) DragEnd() {
+	e.dragging = -1
+}
+
+func (e *curveEditor) generate(w, h int) image.Image {
+	dst := e.background.generate(w, h).(*image.RGBA)
+	if w < 2 || h < 2 {
+		return dst
+	}
+
+	for i := 1; i < 4; i++ {
+		for y := range h {
+			setPixel(dst, i*w/4, y, curveGridColor)
+		}
+		for x := range w {
+			setPixel(dst, x, i*h/4, curveGridColor)
+		}
+	}
+
+	c := e.curve()
+	color := channelColors[curveChannels[e.channel]]
+	table := c.Table()
+
+	prevY := -1
+	for x := range w {
+		level := x * 255 / (w - 1)
+		y := (h - 1) - int(table[level])*(h-1)/255
+		if prevY < 0 {
+			prevY = y
+		}
+		for yy := min(y, prevY); yy <= max(y, prevY); yy++ {
+			setPixel(dst, x, yy, color)
+		}
+		prevY = y
+	}
+
+	bounds := dst.Bounds()
+	for _, pt := range c {
+		px := int(math.Round(pt.X / 255 * float64(w-1)))
+		py := (h - 1) - int(math.Round(pt.Y/255*float64(h-1)))
+		for y := py - pointSize; y <= py+pointSize; y++ {
+			for x := px - pointSize; x <= px+pointSize; x++ {
+				if image.Pt(x, y).In(bounds) {
+					setPixel(dst, x, y, markerColor)
+				}
+			}
+		}
+	}
+
+	return dst
+}
+
+func showCurvesDialog(f filters.Filter, ed *editor) {
+	curves := newCurveEditor(filters.Histograms(ed.img.Image))
+	preview := newPreview(ed, f)
+
+	pointsLabel := widget.NewLabel("")
+	pointsLabel.TextStyle.Monospace = true
+	pointsLabel.Wrapping = fyne.TextWrapWord
+
+	update := func() {
+		pointsLabel.SetText(curves.curve().String())
+		preview.schedule(curves.params())
+	}
+	curves.onChanged = update
+
+	channelRadio := widget.NewRadioGroup(curveChannelNames, func(selected string) {
+		curves.setChannel(slices.Index(curveChannelNames, selected))
+		pointsLabel.SetText(curves.curve().String())
+	})
+	channelRadio.Horizontal = true
+	channelRadio.Required = true
+	channelRadio.SetSelected(curveChannelNames[0])
+
+	resetButton := widget.NewButton("Reset channel", func() {
+		curves.setCurve(filters.IdentityCurve())
+	})
+
+	content := container.NewVBox(
+		container.NewHBox(channelRadio, resetButton),
+		curves,
+		widget.NewLabel("Click to add a point, drag to move it, right click to remove it."),
+		pointsLabel,
+	)
+
+	curvesDialog := showParamsDialog(f.Title(), content, ed.window, func() bool {
+		preview.revert()
+		ed.applyFilter(f, curves.params())
+		return true
+	})
+	curvesDialog.SetOnClosed(preview.revert)
+	curvesDialog.Resize(fyne.NewSize(420, 560))
+
+	update()
+}
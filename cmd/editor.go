@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/engine"
+	"photoshop/filters"
+	"photoshop/history"
+)
+
+const (
+	historyLimitKey     = "historyLimitMB"
+	defaultHistoryLimit = 512
+)
+
+// editor holds the state shared by the panels: the displayed image, the
+// original one and the history of operations between them.
+type editor struct {
+	window  fyne.Window
+	img     *canvas.Image
+	origImg *canvas.Image
+	history *history.History
+
+	// frame is the image space covered by img.Image; it differs from its
+	// bounds only while a downscaled preview is displayed.
+	frame image.Rectangle
+
+	// busy is set while an operation runs in the background; the image
+	// and history are left alone until it finishes.
+	busy bool
+
+	listeners        []func()
+	displayListeners []func()
+}
+
+func newEditor(app fyne.App, window fyne.Window, img, origImg *canvas.Image) *editor {
+	limit := app.Preferences().IntWithFallback(historyLimitKey, defaultHistoryLimit)
+
+	return &editor{
+		window:  window,
+		img:     img,
+		origImg: origImg,
+		history: history.New(int64(limit) << 20),
+	}
+}
+
+// onChange registers fn to be called whenever the image or history changes.
+func (e *editor) onChange(fn func()) {
+	e.listeners = append(e.listeners, fn)
+}
+
+func (e *editor) changed() {
+	for _, fn := range e.listeners {
+		fn()
+	}
+}
+
+// onDisplay registers fn to be called whenever the displayed image changes,
+// including previews that are not part of the history.
+func (e *editor) onDisplay(fn func()) {
+	e.displayListeners = append(e.displayListeners, fn)
+}
+
+// display puts img on screen as covering frame of image space.
+func (e *editor) display(img image.Image, frame image.Rectangle) {
+	e.img.Image = img
+	e.frame = frame
+	e.img.Refresh()
+	for _, fn := range e.displayListeners {
+		fn()
+	}
+}
+
+func (e *editor) show(img image.Image) {
+	e.display(img, img.Bounds())
+	e.changed()
+}
+
+// open starts a new document, discarding the previous history.
+func (e *editor) open(src image.Image) {
+	if e.busy {
+		return
+	}
+	e.origImg.Image = src
+	e.history.Reset(history.Step{Title: "Open", Image: src, Base: true})
+	e.show(src)
+}
+
+// restoreOriginal goes back to the opened image as a new step.
+func (e *editor) restoreOriginal() {
+	e.history.Push(history.Step{Title: "Original", Image: e.origImg.Image, Base: true})
+	e.show(e.origImg.Image)
+}
+
+// commit records a finished operation and displays its result.
+func (e *editor) commit(title string, filter string, params filters.Params, result image.Image) {
+	e.history.Push(history.Step{Title: title, Filter: filter, Params: params, Image: result})
+	e.show(result)
+}
+
+// applyFilter runs f on the current image in the background and commits
+// the result once it is done.
+func (e *editor) applyFilter(f filters.Filter, params filters.Params) {
+	src := e.img.Image
+
+	e.run(f.Title(), func(ctx context.Context) (image.Image, error) {
+		return f.Apply(ctx, src, params)
+	}, func(result image.Image) {
+		e.commit(f.Title(), f.Name(), params, result)
+	})
+}
+
+// run executes work off the UI goroutine behind a progress dialog whose
+// Cancel button aborts it. onDone receives the result on the UI goroutine;
+// errors are reported and a cancelled run is dropped silently.
+func (e *editor) run(title string, work func(ctx context.Context) (image.Image, error), onDone func(image.Image)) {
+	if e.busy {
+		return
+	}
+	e.busy = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bar := widget.NewProgressBar()
+
+	var lastPercent atomic.Int64
+	ctx = engine.WithProgress(ctx, func(fraction float64) {
+		percent := int64(fraction * 100)
+		for {
+			last := lastPercent.Load()
+			if percent <= last {
+				return
+			}
+			if lastPercent.CompareAndSwap(last, percent) {
+				break
+			}
+		}
+		fyne.Do(func() {
+			bar.SetValue(float64(percent) / 100)
+		})
+	})
+
+	content := container.NewVBox(
+		bar,
+		widget.NewLabel(""),
+	)
+
+	progressDialog := dialog.NewCustomWithoutButtons(title, content, e.window)
+
+	var dissmisButton *widget.Button
+	dissmisButton = widget.NewButton("Cancel", func() {
+		cancel()
+		dissmisButton.Disable()
+	})
+
+	fixedSizeButton := container.NewGridWrap(
+		fyne.NewSize(100, 35),
+		dissmisButton,
+	)
+
+	centeredButton := container.NewCenter(fixedSizeButton)
+	content.Add(centeredButton)
+
+	progressDialog.Resize(fyne.NewSize(300, 100))
+	progressDialog.Show()
+
+	go func() {
+		result, err := work(ctx)
+		cancel()
+
+		fyne.Do(func() {
+			e.busy = false
+			progressDialog.Hide()
+
+			switch {
+			case errors.Is(err, context.Canceled):
+			case err != nil:
+				dialog.ShowInformation("Ошибка", err.Error(), e.window)
+			default:
+				onDone(result)
+			}
+		})
+	}()
+}
+
+func (e *editor) undo() {
+	if e.busy {
+		return
+	}
+	if step, ok := e.history.Undo(); ok {
+		e.show(step.Image)
+	}
+}
+
+func (e *editor) redo() {
+	if e.busy {
+		return
+	}
+	if step, ok := e.history.Redo(); ok {
+		e.show(step.Image)
+	}
+}
+
+func (e *editor) jumpTo(i int) {
+	if e.busy || i == e.history.Index() {
+		return
+	}
+	if step, ok := e.history.JumpTo(i); ok {
+		e.show(step.Image)
+	}
+}
+
+// setHistoryLimit changes the memory cap in megabytes and remembers it.
+func (e *editor) setHistoryLimit(megabytes int) {
+	fyne.CurrentApp().Preferences().SetInt(historyLimitKey, megabytes)
+	e.history.SetLimit(int64(megabytes) << 20)
+	e.changed()
+}
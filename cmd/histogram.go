@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+)
+
+var (
+	channelColors = [...][3]uint8{
+		filters.ChannelRed:   {230, 60, 60},
+		filters.ChannelGreen: {60, 200, 60},
+		filters.ChannelBlue:  {70, 110, 240},
+		filters.ChannelLuma:  {200, 200, 200},
+		filters.ChannelAlpha: {150, 120, 200},
+	}
+	histogramBackground = [3]uint8{32, 32, 32}
+	hoverColor          = [3]uint8{255, 220, 0}
+	markerColor         = [3]uint8{255, 255, 255}
+)
+
+// histogramView plots the histograms of several channels, either on top of
+// each other or in separate rows, and reports the level under the mouse.
+type histogramView struct {
+	widget.BaseWidget
+
+	histograms filters.ChannelHistograms
+	visible    [filters.ChannelAlpha + 1]bool
+	overlay    bool
+	log        bool
+	cumulative bool
+
+	// hover is the level under the mouse, or -1.
+	hover   int
+	onHover func(level int)
+	// markers are levels highlighted by a vertical line, e.g. the input
+	// points of Levels.
+	markers []int
+
+	raster *canvas.Raster
+}
+
+func newHistogramView(histograms filters.ChannelHistograms) *histogramView {
+	view := &histogramView{histograms: histograms, overlay: true, hover: -1}
+	view.visible[filters.ChannelLuma] = true
+	view.raster = canvas.NewRaster(view.generate)
+	view.ExtendBaseWidget(view)
+	return view
+}
+
+func (h *histogramView) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(h.raster)
+}
+
+func (h *histogramView) MinSize() fyne.Size {
+	return fyne.NewSize(256, 160)
+}
+
+func (h *histogramView) update() {
+	h.raster.Refresh()
+}
+
+func (h *histogramView) MouseIn(event *desktop.MouseEvent) {
+	h.MouseMoved(event)
+}
+
+func (h *histogramView) MouseMoved(event *desktop.MouseEvent) {
+	level := min(max(int(event.Position.X/h.Size().Width*256), 0), 255)
+	h.setHover(level)
+}
+
+func (h *histogramView) MouseOut() {
+	h.setHover(-1)
+}
+
+func (h *histogramView) setHover(level int) {
+	if level == h.hover {
+		return
+	}
+	h.hover = level
+	h.update()
+	if h.onHover != nil {
+		h.onHover(level)
+	}
+}
+
+func (h *histogramView) shown() []filters.Channel {
+	var res []filters.Channel
+	for _, channel := range filters.Channels() {
+		if h.visible[channel] {
+			res = append(res, channel)
+		}
+	}
+	return res
+}
+
+// scaled maps a count to the 0..1 bar height for the current scale.
+func (h *histogramView) scaled(count, maxCount int) float64 {
+	if maxCount == 0 {
+		return 0
+	}
+	if h.log {
+		return math.Log1p(float64(count)) / math.Log1p(float64(maxCount))
+	}
+	return float64(count) / float64(maxCount)
+}
+
+func (h *histogramView) generate(w, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, height))
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = histogramBackground[0], histogramBackground[1], histogramBackground[2], 255
+	}
+
+	shown := h.shown()
+	if len(shown) == 0 || w == 0 {
+		return dst
+	}
+
+	rows := 1
+	if !h.overlay {
+		rows = len(shown)
+	}
+	if height < rows {
+		return dst
+	}
+	rowHeight := height / rows
+
+	for i, channel := range shown {
+		top := 0
+		if !h.overlay {
+			top = i * rowHeight
+		}
+		h.plot(dst, image.Rect(0, top, w, top+rowHeight), channel)
+	}
+
+	for _, marker := range h.markers {
+		x := (marker*w + w/2) / 256
+		for y := range height {
+			setPixel(dst, x, y, markerColor)
+		}
+	}
+
+	if h.hover >= 0 {
+		x := (h.hover*w + w/2) / 256
+		for y := range height {
+			setPixel(dst, x, y, hoverColor)
+		}
+	}
+
+	return dst
+}
+
+// plot draws the bars and optionally the cumulative curve of one channel
+// into area, adding the colour to what is already there.
+func (h *histogramView) plot(dst *image.RGBA, area image.Rectangle, channel filters.Channel) {
+	// The curve needs at least two rows to run from bottom to top.
+	if area.Dy() < 2 {
+		return
+	}
+
+	histogram := h.histograms[channel]
+	c := channelColors[channel]
+
+	maxCount := 0
+	for _, count := range histogram {
+		maxCount = max(maxCount, count)
+	}
+
+	cumulative := filters.Cumulative(histogram)
+	total := cumulative[255]
+
+	plotHeight := area.Dy() - 1
+	prevCurveY := -1
+
+	for x := area.Min.X; x < area.Max.X; x++ {
+		level := (x - area.Min.X) * 256 / area.Dx()
+
+		barHeight := int(h.scaled(histogram[level], maxCount) * float64(plotHeight))
+		for y := area.Max.Y - barHeight; y < area.Max.Y; y++ {
+			addPixel(dst, x, y, c)
+		}
+
+		if !h.cumulative || total == 0 {
+			continue
+		}
+
+		curveY := area.Max.Y - 1 - int(float64(cumulative[level])/float64(total)*float64(plotHeight))
+		curveY = min(max(curveY, area.Min.Y), area.Max.Y-1)
+		if prevCurveY < 0 {
+			prevCurveY = curveY
+		}
+		for y := min(curveY, prevCurveY); y <= max(curveY, prevCurveY); y++ {
+			setPixel(dst, x, y, [3]uint8{255, 255, 255})
+		}
+		prevCurveY = curveY
+	}
+}
+
+// addPixel adds c to the pixel, so overlaid channels mix like light.
+func addPixel(dst *image.RGBA, x, y int, c [3]uint8) {
+	i := dst.PixOffset(x, y)
+	for k := range c {
+		dst.Pix[i+k] = uint8(min(int(dst.Pix[i+k])+int(c[k]), 255))
+	}
+}
+
+func setPixel(dst *image.RGBA, x, y int, c [3]uint8) {
+	i := dst.PixOffset(x, y)
+	dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = c[0], c[1], c[2]
+}
+
+// formatStats lays out the statistics of the shown channels as a table.
+func formatStats(histograms filters.ChannelHistograms, channels []filters.Channel) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %7s %7s %7s %4s %4s", "", "Mean", "Median", "StdDev", "Min", "Max")
+	for _, channel := range channels {
+		stats := filters.Stats(histograms[channel])
+		fmt.Fprintf(&b, "\n%-10s %7.2f %7d %7.2f %4d %4d", channel, stats.Mean, stats.Median, stats.StdDev, stats.Min, stats.Max)
+	}
+	return b.String()
+}
+
+// formatLevel describes the counts of one level in the shown channels.
+func formatLevel(histograms filters.ChannelHistograms, channels []filters.Channel, level int) string {
+	if level < 0 {
+		return "Move the mouse over the histogram"
+	}
+	parts := []string{fmt.Sprintf("Level %3d:", level)}
+	for _, channel := range channels {
+		parts = append(parts, fmt.Sprintf("%s %d", channel, histograms[channel][level]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func NewCreateHistogramButton(img *canvas.Image, window fyne.Window) fyne.CanvasObject {
+	return widget.NewButton("Histogram", func() {
+		if img.Image == nil {
+			return
+		}
+		showHistogramDialog(filters.Histograms(img.Image), window)
+	})
+}
+
+func showHistogramDialog(histograms filters.ChannelHistograms, window fyne.Window) {
+	view := newHistogramView(histograms)
+
+	readout := widget.NewLabel("")
+	readout.TextStyle.Monospace = true
+	statsLabel := widget.NewLabel("")
+	statsLabel.TextStyle.Monospace = true
+
+	refresh := func() {
+		view.update()
+		readout.SetText(formatLevel(histograms, view.shown(), view.hover))
+		statsLabel.SetText(formatStats(histograms, view.shown()))
+	}
+	view.onHover = func(int) { refresh() }
+
+	channelChecks := container.NewHBox()
+	for _, channel := range filters.Channels() {
+		check := widget.NewCheck(channel.String(), func(checked bool) {
+			view.visible[channel] = checked
+			refresh()
+		})
+		check.Checked = view.visible[channel]
+		channelChecks.Add(check)
+	}
+
+	layoutRadio := widget.NewRadioGroup([]string{"Overlay", "Separate"}, func(selected string) {
+		view.overlay = selected == "Overlay"
+		refresh()
+	})
+	layoutRadio.Horizontal = true
+	layoutRadio.Required = true
+	layoutRadio.SetSelected("Overlay")
+
+	logCheck := widget.NewCheck("Log scale", func(checked bool) {
+		view.log = checked
+		refresh()
+	})
+	cumulativeCheck := widget.NewCheck("Cumulative", func(checked bool) {
+		view.cumulative = checked
+		refresh()
+	})
+
+	controls := container.NewVBox(
+		channelChecks,
+		container.NewHBox(layoutRadio, widget.NewSeparator(), logCheck, cumulativeCheck),
+		readout,
+		statsLabel,
+	)
+
+	refresh()
+
+	content := container.NewBorder(nil, controls, nil, nil, view)
+
+	histogramDialog := dialog.NewCustomWithoutButtons("Гистограмма", content, window)
+
+	confirmButton := widget.NewButton("OK", func() {
+		histogramDialog.Hide()
+	})
+
+	fixedSizeButton := container.NewGridWrap(
+		fyne.NewSize(100, 35),
+		confirmButton,
+	)
+
+	controls.Add(container.NewCenter(fixedSizeButton))
+
+	histogramDialog.Resize(fyne.NewSize(640, 600))
+	histogramDialog.Show()
+}
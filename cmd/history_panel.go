@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	undoShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}
+	redoShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+)
+
+var historyLimits = []int{128, 256, 512, 1024, 2048}
+
+// NewHistoryPanel lists the recorded steps; selecting one jumps back to it.
+func NewHistoryPanel(ed *editor) fyne.CanvasObject {
+	steps := widget.NewList(
+		func() int {
+			return ed.history.Len()
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			label.SetText(ed.history.Step(id).Label())
+			if id == ed.history.Index() {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+			} else {
+				label.TextStyle = fyne.TextStyle{}
+			}
+			label.Refresh()
+		},
+	)
+	steps.OnSelected = func(id widget.ListItemID) {
+		ed.jumpTo(id)
+	}
+
+	undoButton := widget.NewButton("Undo", ed.undo)
+	redoButton := widget.NewButton("Redo", ed.redo)
+
+	limitOptions := make([]string, len(historyLimits))
+	for i, limit := range historyLimits {
+		limitOptions[i] = strconv.Itoa(limit) + " MB"
+	}
+	limitSelect := widget.NewSelect(limitOptions, func(selected string) {
+		for i, option := range limitOptions {
+			if option == selected && int64(historyLimits[i])<<20 != ed.history.Limit() {
+				ed.setHistoryLimit(historyLimits[i])
+			}
+		}
+	})
+	limitSelect.SetSelected(strconv.FormatInt(ed.history.Limit()>>20, 10) + " MB")
+
+	usage := widget.NewLabel("")
+
+	ed.onChange(func() {
+		if ed.history.CanUndo() {
+			undoButton.Enable()
+		} else {
+			undoButton.Disable()
+		}
+		if ed.history.CanRedo() {
+			redoButton.Enable()
+		} else {
+			redoButton.Disable()
+		}
+
+		usage.SetText("Memory: " + strconv.FormatInt(ed.history.Size()>>20, 10) + " MB")
+
+		steps.Refresh()
+		if index := ed.history.Index(); index >= 0 {
+			steps.Select(index)
+			steps.ScrollTo(index)
+		}
+	})
+	undoButton.Disable()
+	redoButton.Disable()
+
+	top := container.NewVBox(
+		widget.NewLabel("History"),
+		container.NewGridWithColumns(2, undoButton, redoButton),
+	)
+	bottom := container.NewVBox(
+		usage,
+		widget.NewForm(widget.NewFormItem("Limit", limitSelect)),
+	)
+
+	return container.NewBorder(top, bottom, nil, nil, steps)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/colorspace"
+	"photoshop/filters"
+)
+
+// maxPins caps the pinned samples; pinning one more drops the oldest.
+const maxPins = 8
+
+// describePixel formats everything the inspector shows about one pixel.
+func describePixel(pt image.Point, c color.NRGBA) string {
+	gray := filters.Luminance(uint32(c.R), uint32(c.G), uint32(c.B))
+	h, s, v := colorspace.RGBToHSV(c.R, c.G, c.B)
+	l, la, lb := colorspace.RGBToLab(c.R, c.G, c.B)
+
+	hex := fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	if c.A != 255 {
+		hex += fmt.Sprintf("%02X", c.A)
+	}
+
+	return fmt.Sprintf("X %4d  Y %4d   RGBA %3d %3d %3d %3d   Gray %5.1f   HSV %3.0f° %3.0f%% %3.0f%%   Lab %3.0f %4.0f %4.0f   %s",
+		pt.X, pt.Y, c.R, c.G, c.B, c.A, gray, h, s*100, v*100, l, la, lb, hex)
+}
+
+// newInspector builds the status bar: the pixel under the mouse and the
+// samples pinned by clicking the image, which are read again from current
+// whenever the displayed image changes.
+func newInspector(ed *editor, vp *viewport, current layer) fyne.CanvasObject {
+	cursorLabel := widget.NewLabel("Click the image to pin a sample")
+	cursorLabel.TextStyle.Monospace = true
+
+	pinList := container.NewVBox()
+	var pins []image.Point
+
+	var updatePins func()
+	updatePins = func() {
+		pinList.Objects = nil
+
+		for i, pin := range pins {
+			text := fmt.Sprintf("X %4d  Y %4d   outside the image", pin.X, pin.Y)
+			if c, ok := pixelAt(current, pin); ok {
+				text = describePixel(pin, c)
+			}
+
+			pinLabel := widget.NewLabel(fmt.Sprintf("%d. %s", i+1, text))
+			pinLabel.TextStyle.Monospace = true
+
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				pins = slices.Delete(pins, i, i+1)
+				vp.setPins(slices.Clone(pins))
+				updatePins()
+			})
+
+			pinList.Add(container.NewBorder(nil, nil, nil, removeButton, pinLabel))
+		}
+
+		pinList.Refresh()
+	}
+
+	vp.onHover = func(pt image.Point, c color.NRGBA, ok bool) {
+		if !ok {
+			cursorLabel.SetText("")
+			return
+		}
+		cursorLabel.SetText(describePixel(pt, c))
+	}
+
+	vp.onTap = func(pt image.Point) {
+		if len(pins) == maxPins {
+			pins = slices.Delete(pins, 0, 1)
+		}
+		pins = append(pins, pt)
+		vp.setPins(slices.Clone(pins))
+		updatePins()
+	}
+
+	ed.onDisplay(updatePins)
+
+	return container.NewVBox(pinList, cursorLabel)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/engine"
+	"photoshop/filters"
+)
+
+const (
+	maxKernelSide     = 15
+	previewSide       = 240
+	customKernelTitle = "Custom kernel"
+)
+
+var kernelSides = func() []string {
+	var sides []string
+	for side := 1; side <= maxKernelSide; side += 2 {
+		sides = append(sides, strconv.Itoa(side))
+	}
+	return sides
+}()
+
+// kernelEditor is the state of the custom kernel dialog: the grid of
+// weights, the remaining kernel fields and a preview on a small copy of
+// the current image.
+type kernelEditor struct {
+	width, height int
+	cells         []*widget.Entry
+	grid          *fyne.Container
+
+	widthSelect, heightSelect *widget.Select
+	divisor, bias             *widget.Entry
+	normalize, gray, abs      *widget.Check
+	border                    *widget.Select
+
+	proxy   image.Image
+	preview *canvas.Image
+
+	// loading suppresses previews while several fields change at once.
+	loading bool
+}
+
+func NewCustomKernelButton(ed *editor) fyne.CanvasObject {
+	return widget.NewButton(customKernelTitle, func() {
+		if ed.img.Image == nil {
+			return
+		}
+		showKernelEditor(ed)
+	})
+}
+
+func showKernelEditor(ed *editor) {
+	k := &kernelEditor{
+		grid:    container.NewGridWithColumns(1),
+		proxy:   engine.Thumbnail(ed.img.Image, previewSide),
+		preview: canvas.NewImageFromImage(nil),
+	}
+	k.preview.FillMode = canvas.ImageFillContain
+	k.preview.SetMinSize(fyne.NewSize(previewSide, previewSide))
+
+	refresh := func(string) { k.updatePreview() }
+
+	k.widthSelect = widget.NewSelect(kernelSides, func(string) { k.resize() })
+	k.heightSelect = widget.NewSelect(kernelSides, func(string) { k.resize() })
+	k.divisor = widget.NewEntry()
+	k.divisor.OnChanged = refresh
+	k.bias = widget.NewEntry()
+	k.bias.OnChanged = refresh
+	k.normalize = widget.NewCheck("Normalize", func(bool) { k.updatePreview() })
+	k.gray = widget.NewCheck("Luminance only", func(bool) { k.updatePreview() })
+	k.abs = widget.NewCheck("Absolute value", func(bool) { k.updatePreview() })
+	k.border = widget.NewSelect(filters.BorderModeNames(), refresh)
+	k.border.SetSelected(filters.BorderClamp.String())
+
+	presets := filters.KernelPresets()
+	presetNames := make([]string, len(presets))
+	for i, preset := range presets {
+		presetNames[i] = preset.Name
+	}
+	presetSelect := widget.NewSelect(presetNames, func(name string) {
+		for _, preset := range presets {
+			if preset.Name == name {
+				k.load(preset)
+				return
+			}
+		}
+	})
+	presetSelect.PlaceHolder = "Preset"
+
+	importButton := widget.NewButton("Import...", func() { k.importKernel(ed.window) })
+	exportButton := widget.NewButton("Export...", func() { k.exportKernel(ed.window) })
+
+	form := widget.NewForm(
+		widget.NewFormItem("Preset", presetSelect),
+		widget.NewFormItem("Size", container.NewGridWithColumns(2, k.widthSelect, k.heightSelect)),
+		widget.NewFormItem("Kernel", k.grid),
+		widget.NewFormItem("Divisor", k.divisor),
+		widget.NewFormItem("Offset", k.bias),
+		widget.NewFormItem("Border", k.border),
+	)
+
+	left := container.NewVBox(
+		form,
+		container.NewHBox(k.normalize, k.gray, k.abs),
+		container.NewGridWithColumns(2, importButton, exportButton),
+	)
+
+	content := container.NewVBox(container.NewHBox(left, container.NewCenter(k.preview)))
+
+	k.load(presets[0])
+
+	kernelDialog := showParamsDialog(customKernelTitle, content, ed.window, func() bool {
+		preset, ok := k.read()
+		if !ok {
+			dialog.ShowInformation("Ошибка", "Введите корректное число", ed.window)
+			return false
+		}
+
+		border := k.borderMode()
+		src := ed.img.Image
+		ed.run(customKernelTitle, func(ctx context.Context) (image.Image, error) {
+			return filters.Convolve(ctx, src, preset.Kernel, preset.Options(border))
+		}, func(result image.Image) {
+			ed.commit(customKernelTitle, "", nil, result)
+		})
+		return true
+	})
+	kernelDialog.Resize(fyne.NewSize(760, 520))
+}
+
+// resize rebuilds the grid for the selected size, keeping the weights that
+// still fit.
+func (k *kernelEditor) resize() {
+	width, err1 := strconv.Atoi(k.widthSelect.Selected)
+	height, err2 := strconv.Atoi(k.heightSelect.Selected)
+	if err1 != nil || err2 != nil || (width == k.width && height == k.height) {
+		return
+	}
+
+	cells := make([]*widget.Entry, width*height)
+	for y := range height {
+		for x := range width {
+			cell := widget.NewEntry()
+			cell.SetText("0")
+			if x < k.width && y < k.height {
+				cell.SetText(k.cells[y*k.width+x].Text)
+			}
+			cell.OnChanged = func(string) { k.updatePreview() }
+			cells[y*width+x] = cell
+		}
+	}
+
+	k.width, k.height, k.cells = width, height, cells
+
+	k.grid.Layout = layout.NewGridLayoutWithColumns(width)
+	k.grid.Objects = k.grid.Objects[:0]
+	for _, cell := range cells {
+		k.grid.Objects = append(k.grid.Objects, cell)
+	}
+	k.grid.Refresh()
+
+	k.updatePreview()
+}
+
+// fitsEditor reports whether the grid can show kernel.
+func fitsEditor(kernel filters.Kernel) bool {
+	return kernel.Width%2 == 1 && kernel.Height%2 == 1 && kernel.Width <= maxKernelSide && kernel.Height <= maxKernelSide
+}
+
+func (k *kernelEditor) load(preset filters.KernelPreset) {
+	k.loading = true
+
+	kernel := preset.Kernel
+	k.widthSelect.SetSelected(strconv.Itoa(kernel.Width))
+	k.heightSelect.SetSelected(strconv.Itoa(kernel.Height))
+	for i, cell := range k.cells {
+		cell.SetText(formatValue(kernel.Data[i]))
+	}
+
+	divisor := kernel.Divisor
+	if divisor == 0 && !kernel.Normalize {
+		divisor = 1
+	}
+	k.divisor.SetText(formatValue(divisor))
+	k.bias.SetText(formatValue(kernel.Bias))
+	k.normalize.SetChecked(kernel.Normalize)
+	k.gray.SetChecked(preset.Gray)
+	k.abs.SetChecked(preset.Abs)
+
+	k.loading = false
+	k.updatePreview()
+}
+
+// read collects the dialog fields into a preset, or reports false when a
+// field does not hold a number.
+func (k *kernelEditor) read() (filters.KernelPreset, bool) {
+	kernel := filters.Kernel{Width: k.width, Height: k.height, Normalize: k.normalize.Checked}
+
+	for _, cell := range k.cells {
+		value, ok := filters.ParseNumber(cell.Text)
+		if !ok {
+			return filters.KernelPreset{}, false
+		}
+		kernel.Data = append(kernel.Data, value)
+	}
+
+	var ok bool
+	if kernel.Divisor, ok = filters.ParseNumber(k.divisor.Text); !ok {
+		return filters.KernelPreset{}, false
+	}
+	if kernel.Bias, ok = filters.ParseNumber(k.bias.Text); !ok {
+		return filters.KernelPreset{}, false
+	}
+	if kernel.Validate() != nil {
+		return filters.KernelPreset{}, false
+	}
+
+	return filters.KernelPreset{Name: customKernelTitle, Kernel: kernel, Gray: k.gray.Checked, Abs: k.abs.Checked}, true
+}
+
+func (k *kernelEditor) borderMode() filters.BorderMode {
+	return filters.BorderMode(k.border.SelectedIndex())
+}
+
+// updatePreview runs the kernel on the proxy image; the preview keeps its
+// last picture while the fields are invalid.
+func (k *kernelEditor) updatePreview() {
+	if k.loading {
+		return
+	}
+
+	preset, ok := k.read()
+	if !ok {
+		return
+	}
+
+	result, err := filters.Convolve(context.Background(), k.proxy, preset.Kernel, preset.Options(k.borderMode()))
+	if err != nil {
+		return
+	}
+
+	k.preview.Image = result
+	k.preview.Refresh()
+}
+
+func (k *kernelEditor) importKernel(window fyne.Window) {
+	openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		preset, err := filters.ReadKernelPreset(reader)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if !fitsEditor(preset.Kernel) {
+			dialog.ShowError(fmt.Errorf("ядро %dx%d: стороны должны быть нечётными и не больше %d", preset.Kernel.Width, preset.Kernel.Height, maxKernelSide), window)
+			return
+		}
+		k.load(preset)
+	}, window)
+
+	openDialog.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	openDialog.Show()
+}
+
+func (k *kernelEditor) exportKernel(window fyne.Window) {
+	preset, ok := k.read()
+	if !ok {
+		dialog.ShowInformation("Ошибка", "Введите корректное число", window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		err = filters.WriteKernelPreset(writer, preset)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			storage.Delete(writer.URI())
+			dialog.ShowError(err, window)
+		}
+	}, window)
+
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	saveDialog.SetFileName("kernel.json")
+	saveDialog.Show()
+}
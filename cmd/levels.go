@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+
+	"photoshop/filters"
+)
+
+// levelsChannels maps the channel choices of the levels filter to the
+// histogram drawn behind the sliders.
+var levelsChannels = map[string]filters.Channel{
+	"RGB":   filters.ChannelLuma,
+	"Red":   filters.ChannelRed,
+	"Green": filters.ChannelGreen,
+	"Blue":  filters.ChannelBlue,
+}
+
+// showLevelsDialog is the generated parameter dialog of the levels filter
+// with the histogram of the selected channel above it, marking the input
+// black point, the midtone the gamma maps to 50% and the white point.
+func showLevelsDialog(f filters.Filter, ed *editor) {
+	view := newHistogramView(filters.Histograms(ed.img.Image))
+	preview := newPreview(ed, f)
+
+	schema := f.Params()
+	inputs := make([]paramInput, len(schema))
+	content := container.NewVBox(view)
+	for i, param := range schema {
+		inputs[i] = newParamInput(param)
+		content.Add(inputs[i].object())
+	}
+
+	update := func() {
+		params, ok := readParams(schema, inputs)
+		if !ok {
+			return
+		}
+
+		view.visible = [filters.ChannelAlpha + 1]bool{}
+		view.visible[levelsChannels[params.String("channel")]] = true
+
+		black, white := params.Int("in-black"), params.Int("in-white")
+		midtone := black + int(math.Round(float64(white-black)*math.Pow(0.5, params.Float("gamma"))))
+		view.markers = []int{black, midtone, white}
+		view.update()
+
+		preview.schedule(params)
+	}
+	for _, input := range inputs {
+		input.setOnChanged(update)
+	}
+
+	levelsDialog := showParamsDialog(f.Title(), content, ed.window, func() bool {
+		params, ok := readParams(schema, inputs)
+		if !ok {
+			dialog.ShowInformation("Ошибка", "Введите корректное число", ed.window)
+			return false
+		}
+		preview.revert()
+		ed.applyFilter(f, params)
+		return true
+	})
+	levelsDialog.SetOnClosed(preview.revert)
+	levelsDialog.Resize(fyne.NewSize(420, 600))
+
+	update()
+}
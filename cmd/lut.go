@@ -0,0 +1,135 @@
+package main
+
+import (
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+	"photoshop/lut"
+)
+
+// showApplyLUTDialog replaces the text entry of the apply-lut filter with
+// a file chooser and previews the chosen .cube file.
+func showApplyLUTDialog(f filters.Filter, ed *editor) {
+	preview := newPreview(ed, f)
+
+	path := ""
+	interpolation := lut.Tetrahedral.String()
+
+	fileLabel := widget.NewLabel("No file chosen")
+	fileLabel.Wrapping = fyne.TextWrapBreak
+
+	params := func() filters.Params {
+		return filters.Params{"file": path, "interpolation": interpolation}
+	}
+	update := func() {
+		if path != "" {
+			preview.schedule(params())
+		}
+	}
+
+	chooseButton := widget.NewButton("Choose .cube file…", func() {
+		openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, ed.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+
+			path = reader.URI().Path()
+			fileLabel.SetText(filepath.Base(path))
+			update()
+		}, ed.window)
+		openDialog.SetFilter(storage.NewExtensionFileFilter([]string{".cube"}))
+		openDialog.Show()
+	})
+
+	interpolationRadio := widget.NewRadioGroup(lut.InterpolationNames(), func(selected string) {
+		interpolation = selected
+		update()
+	})
+	interpolationRadio.Horizontal = true
+	interpolationRadio.Required = true
+	interpolationRadio.SetSelected(interpolation)
+
+	content := container.NewVBox(
+		chooseButton,
+		fileLabel,
+		widget.NewLabel("Interpolation of 3D tables"),
+		interpolationRadio,
+	)
+
+	lutDialog := showParamsDialog(f.Title(), content, ed.window, func() bool {
+		if path == "" {
+			dialog.ShowInformation("Ошибка", "Выберите файл .cube", ed.window)
+			return false
+		}
+		preview.revert()
+		ed.applyFilter(f, params())
+		return true
+	})
+	lutDialog.SetOnClosed(preview.revert)
+	lutDialog.Resize(fyne.NewSize(360, 240))
+}
+
+// historyLUT chains the lookup tables of the filters applied since the
+// image was opened or reset to the original, up to the current step.
+func historyLUT(ed *editor) (lut.LUT, error) {
+	history, err := ed.history.FilterSteps()
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]filters.ChainStep, len(history))
+	for i, step := range history {
+		steps[i] = filters.ChainStep{Filter: step.Filter, Params: step.Params}
+	}
+	return filters.ChainLUT(steps)
+}
+
+// NewExportLUTButton saves the colour mappings in the history as a .cube
+// file that other editors can apply.
+func NewExportLUTButton(ed *editor) fyne.CanvasObject {
+	return widget.NewButton("Export LUT", func() {
+		if ed.img.Image == nil {
+			return
+		}
+		chain, err := historyLUT(ed)
+		if err != nil {
+			dialog.ShowError(err, ed.window)
+			return
+		}
+
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, ed.window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+
+			title := filepath.Base(writer.URI().Path())
+			err = lut.WriteCube(writer, &lut.Cube{Title: title[:len(title)-len(filepath.Ext(title))], LUT: chain})
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				storage.Delete(writer.URI())
+				dialog.ShowError(err, ed.window)
+			}
+		}, ed.window)
+
+		saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".cube"}))
+		saveDialog.SetFileName("grade.cube")
+		saveDialog.Show()
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+	"photoshop/imageio"
+)
+
+const swatchSide = 20
+
+var swatchStrokeColor = color.Gray{Y: 80}
+
+// setSwatches fills grid with one square per opaque palette entry.
+func setSwatches(grid *fyne.Container, palette color.Palette) {
+	grid.RemoveAll()
+	for _, c := range palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			continue
+		}
+		swatch := canvas.NewRectangle(c)
+		swatch.StrokeColor = swatchStrokeColor
+		swatch.StrokeWidth = 1
+		grid.Add(swatch)
+	}
+	grid.Refresh()
+}
+
+func newSwatchGrid() *fyne.Container {
+	return container.NewGridWrap(fyne.NewSize(swatchSide, swatchSide))
+}
+
+// showPaletteDialog is the generated parameter dialog of the palette filter
+// with the swatches of the palette picked for the preview.
+func showPaletteDialog(f filters.Filter, ed *editor) {
+	preview := newPreview(ed, f)
+
+	swatches := newSwatchGrid()
+	countLabel := widget.NewLabel("")
+	preview.onResult = func(result image.Image) {
+		if paletted, ok := result.(*image.Paletted); ok {
+			setSwatches(swatches, paletted.Palette)
+			countLabel.SetText(fmt.Sprintf("Preview palette: %d colours", len(swatches.Objects)))
+		}
+	}
+
+	schema := f.Params()
+	inputs := make([]paramInput, len(schema))
+	content := container.NewVBox()
+	for i, param := range schema {
+		inputs[i] = newParamInput(param)
+		content.Add(inputs[i].object())
+	}
+	content.Add(countLabel)
+	content.Add(swatches)
+
+	update := func() {
+		if params, ok := readParams(schema, inputs); ok {
+			preview.schedule(params)
+		}
+	}
+	for _, input := range inputs {
+		input.setOnChanged(update)
+	}
+
+	paletteDialog := showParamsDialog(f.Title(), content, ed.window, func() bool {
+		params, ok := readParams(schema, inputs)
+		if !ok {
+			dialog.ShowInformation("Ошибка", "Введите корректное число", ed.window)
+			return false
+		}
+		preview.revert()
+		ed.applyFilter(f, params)
+		return true
+	})
+	paletteDialog.SetOnClosed(preview.revert)
+	paletteDialog.Resize(fyne.NewSize(460, 560))
+
+	update()
+}
+
+// NewPaletteButton shows the palette of an indexed image, e.g. after
+// "Reduce to palette", and exports it as a GIMP palette.
+func NewPaletteButton(ed *editor) fyne.CanvasObject {
+	return widget.NewButton("Palette", func() {
+		paletted, ok := ed.img.Image.(*image.Paletted)
+		if !ok {
+			dialog.ShowInformation("Palette", "The image has no palette; reduce it with \"Reduce to palette\" first.", ed.window)
+			return
+		}
+
+		swatches := newSwatchGrid()
+		setSwatches(swatches, paletted.Palette)
+
+		exportButton := widget.NewButton("Export .gpl…", func() {
+			exportPalette(paletted.Palette, ed.window)
+		})
+
+		content := container.NewBorder(
+			widget.NewLabel(fmt.Sprintf("%d colours; save as PNG or GIF for an indexed file.", len(swatches.Objects))),
+			container.NewCenter(exportButton), nil, nil,
+			container.NewVScroll(swatches),
+		)
+
+		paletteDialog := dialog.NewCustom("Palette", "OK", content, ed.window)
+		paletteDialog.Resize(fyne.NewSize(420, 360))
+		paletteDialog.Show()
+	})
+}
+
+func exportPalette(palette color.Palette, window fyne.Window) {
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		name := writer.URI().Name()
+		err = imageio.WriteGPL(writer, strings.TrimSuffix(name, filepath.Ext(name)), palette)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			storage.Delete(writer.URI())
+			dialog.ShowError(err, window)
+		}
+	}, window)
+
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".gpl"}))
+	saveDialog.SetFileName("palette.gpl")
+	saveDialog.Show()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+)
+
+// NewFilterPanel builds the left-hand panel: the tool buttons followed by
+// one collapsible section per filter category.
+func NewFilterPanel(ed *editor) fyne.CanvasObject {
+	accordion := widget.NewAccordion()
+	accordion.MultiOpen = true
+
+	for _, category := range filters.Categories() {
+		section := container.NewVBox()
+		for _, f := range filters.ByCategory(category) {
+			section.Add(NewFilterButton(f, ed))
+		}
+		if len(section.Objects) == 0 {
+			continue
+		}
+
+		item := widget.NewAccordionItem(string(category), section)
+		item.Open = true
+		accordion.Append(item)
+	}
+
+	return container.NewVBox(
+		NewOriginalButton(ed),
+		NewCreateHistogramButton(ed.img, ed.window),
+		NewSaveButton(ed.img, ed.window),
+		NewCustomKernelButton(ed),
+		NewExportLUTButton(ed),
+		NewPaletteButton(ed),
+		accordion,
+	)
+}
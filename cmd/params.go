@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/filters"
+)
+
+// paramInput is the widget editing a single filter parameter.
+type paramInput interface {
+	object() fyne.CanvasObject
+	// value returns the parsed value, or false if the input is invalid.
+	value() (any, bool)
+	// setOnChanged registers fn to be called after every edit.
+	setOnChanged(fn func())
+}
+
+func newParamInput(param filters.Param) paramInput {
+	switch {
+	case param.Kind == filters.ParamChoice:
+		return newChoiceInput(param)
+	case param.Slider:
+		return newSliderInput(param)
+	}
+	return newEntryInput(param)
+}
+
+func readParams(schema []filters.Param, inputs []paramInput) (filters.Params, bool) {
+	params := make(filters.Params, len(schema))
+	for i, param := range schema {
+		value, ok := inputs[i].value()
+		if !ok {
+			return nil, false
+		}
+		params[param.Name] = value
+	}
+	return params, true
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return ""
+}
+
+type entryInput struct {
+	param filters.Param
+	entry *widget.Entry
+}
+
+func newEntryInput(param filters.Param) *entryInput {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder(param.Label)
+	entry.SetText(formatValue(param.Default))
+
+	return &entryInput{param: param, entry: entry}
+}
+
+func (e *entryInput) object() fyne.CanvasObject {
+	return e.entry
+}
+
+func (e *entryInput) setOnChanged(fn func()) {
+	e.entry.OnChanged = func(string) { fn() }
+}
+
+func (e *entryInput) value() (any, bool) {
+	if e.param.Kind == filters.ParamText {
+		return e.entry.Text, true
+	}
+	if e.param.Kind == filters.ParamInt {
+		value, err := strconv.Atoi(strings.TrimSpace(e.entry.Text))
+		return value, err == nil
+	}
+	return filters.ParseNumber(e.entry.Text)
+}
+
+type sliderInput struct {
+	param  filters.Param
+	slider *widget.Slider
+	box    *fyne.Container
+	// setLabel shows the current value above the slider.
+	setLabel func(float64)
+}
+
+func newSliderInput(param filters.Param) *sliderInput {
+	slider := widget.NewSlider(param.Min, param.Max)
+	slider.Step = param.Step
+	switch v := param.Default.(type) {
+	case int:
+		slider.Value = float64(v)
+	case float64:
+		slider.Value = v
+	}
+
+	valueLabel := widget.NewLabel("")
+	setLabel := func(value float64) {
+		if param.Kind == filters.ParamInt {
+			valueLabel.SetText(param.Label + ": " + strconv.Itoa(int(value)))
+		} else {
+			valueLabel.SetText(param.Label + ": " + strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	setLabel(slider.Value)
+	slider.OnChanged = setLabel
+
+	return &sliderInput{
+		param:    param,
+		slider:   slider,
+		box:      container.NewVBox(valueLabel, slider),
+		setLabel: setLabel,
+	}
+}
+
+func (s *sliderInput) setOnChanged(fn func()) {
+	s.slider.OnChanged = func(value float64) {
+		s.setLabel(value)
+		fn()
+	}
+}
+
+func (s *sliderInput) object() fyne.CanvasObject {
+	return s.box
+}
+
+func (s *sliderInput) value() (any, bool) {
+	if s.param.Kind == filters.ParamInt {
+		return int(s.slider.Value), true
+	}
+	return s.slider.Value, true
+}
+
+type choiceInput struct {
+	selection *widget.Select
+}
+
+func newChoiceInput(param filters.Param) *choiceInput {
+	selection := widget.NewSelect(param.Choices, nil)
+	selection.SetSelected(formatValue(param.Default))
+
+	return &choiceInput{selection: selection}
+}
+
+func (c *choiceInput) object() fyne.CanvasObject {
+	return c.selection
+}
+
+func (c *choiceInput) setOnChanged(fn func()) {
+	c.selection.OnChanged = func(string) { fn() }
+}
+
+func (c *choiceInput) value() (any, bool) {
+	return c.selection.Selected, c.selection.Selected != ""
+}
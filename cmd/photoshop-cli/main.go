@@ -0,0 +1,13 @@
+// Command photoshop-cli is the command-line mode of photoshop built without
+// the GUI, for machines that have no display or OpenGL libraries.
+package main
+
+import (
+	"os"
+
+	"photoshop/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"image"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"photoshop/engine"
+	"photoshop/filters"
+)
+
+const (
+	// previewDelay is how long the parameters have to stay unchanged before
+	// a preview is rendered.
+	previewDelay = 150 * time.Millisecond
+	// previewMaxSide caps the proxy the preview is computed on.
+	previewMaxSide = 1024
+)
+
+// preview shows a filter applied to a downscaled copy of the current image
+// in the main canvas while its parameter dialog is open. The history is
+// not touched: revert puts the committed image back.
+type preview struct {
+	ed     *editor
+	filter filters.Filter
+	src    image.Image
+	proxy  image.Image
+	// onReport receives the filter's report on the full image when the
+	// filter is a filters.Reporter.
+	onReport func(text string)
+	// onResult receives every rendered preview image, e.g. to show the
+	// palette a filter picked.
+	onResult func(result image.Image)
+
+	timer      *time.Timer
+	cancel     context.CancelFunc
+	generation int
+	stopped    bool
+}
+
+func newPreview(ed *editor, f filters.Filter) *preview {
+	return &preview{
+		ed:     ed,
+		filter: f,
+		src:    ed.img.Image,
+		proxy:  engine.Thumbnail(ed.img.Image, previewMaxSide),
+	}
+}
+
+// schedule renders params once they have not changed for previewDelay.
+func (p *preview) schedule(params filters.Params) {
+	if p.stopped {
+		return
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.timer = time.AfterFunc(previewDelay, func() {
+		fyne.Do(func() {
+			p.render(params)
+		})
+	})
+}
+
+// render starts computing params in the background, abandoning the
+// previous render; only the latest one reaches the canvas.
+func (p *preview) render(params filters.Params) {
+	if p.stopped {
+		return
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.generation++
+	generation := p.generation
+
+	go func() {
+		result, err := p.filter.Apply(ctx, p.proxy, params)
+
+		var report string
+		if reporter, ok := p.filter.(filters.Reporter); ok && err == nil {
+			report, err = reporter.Report(ctx, p.src, params)
+		}
+		cancel()
+
+		fyne.Do(func() {
+			if p.stopped || generation != p.generation || err != nil {
+				return
+			}
+			p.ed.display(result, p.src.Bounds())
+			if p.onReport != nil {
+				p.onReport(report)
+			}
+			if p.onResult != nil {
+				p.onResult(result)
+			}
+		})
+	}()
+}
+
+// revert stops pending renders and shows the committed image again.
+func (p *preview) revert() {
+	p.stopped = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	p.ed.display(p.src, p.src.Bounds())
+}
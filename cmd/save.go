@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/imageio"
+)
+
+var saveShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault}
+
+func NewSaveButton(img *canvas.Image, window fyne.Window) fyne.CanvasObject {
+	return widget.NewButton("Save as", func() {
+		showSaveDialog(img, window)
+	})
+}
+
+// showSaveDialog asks for a destination and encodes the current image with
+// the codec matching its extension.
+func showSaveDialog(img *canvas.Image, window fyne.Window) {
+	if img.Image == nil {
+		return
+	}
+
+	current := img.Image
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		format, err := imageio.FormatFromPath(writer.URI().Path())
+		if err != nil {
+			writer.Close()
+			storage.Delete(writer.URI())
+			dialog.ShowError(err, window)
+			return
+		}
+
+		if format != imageio.JPEG {
+			writeImage(writer, current, format, imageio.Options{}, window)
+			return
+		}
+
+		askJPEGQuality(window, func(quality int, ok bool) {
+			if !ok {
+				writer.Close()
+				storage.Delete(writer.URI())
+				return
+			}
+			writeImage(writer, current, format, imageio.Options{Quality: quality}, window)
+		})
+	}, window)
+
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg", ".gif", ".bmp"}))
+	saveDialog.SetFileName("image.png")
+	saveDialog.Show()
+}
+
+func writeImage(writer fyne.URIWriteCloser, current image.Image, format imageio.Format, opts imageio.Options, window fyne.Window) {
+	err := imageio.Encode(writer, current, format, opts)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		storage.Delete(writer.URI())
+		dialog.ShowError(fmt.Errorf("не удалось сохранить %s: %w", writer.URI().Name(), err), window)
+	}
+}
+
+func askJPEGQuality(window fyne.Window, onDone func(quality int, ok bool)) {
+	qualitySlider := widget.NewSlider(1, 100)
+	qualitySlider.Value = 90
+	qualitySlider.Step = 1
+
+	valueLabel := widget.NewLabel("Quality: " + strconv.Itoa(int(qualitySlider.Value)))
+
+	qualitySlider.OnChanged = func(value float64) {
+		valueLabel.SetText("Quality: " + strconv.Itoa(int(value)))
+	}
+
+	content := container.NewVBox(
+		valueLabel,
+		qualitySlider,
+	)
+
+	confirmed := false
+	qualityDialog := showParamsDialog("JPEG quality", content, window, func() bool {
+		confirmed = true
+		onDone(int(qualitySlider.Value), true)
+		return true
+	})
+	qualityDialog.SetOnClosed(func() {
+		if !confirmed {
+			onDone(0, false)
+		}
+	})
+}
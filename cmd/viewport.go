@@ -0,0 +1,498 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	"photoshop/engine"
+)
+
+const (
+	minZoom = 1. / 64
+	maxZoom = 64
+	// wheelZoomStep is the zoom factor of one wheel notch.
+	wheelZoomStep = 1.1
+	// dividerGrab is how close to the divider, in units, a drag moves it.
+	dividerGrab = 6
+	// gridMinZoom is the zoom from which the pixel grid is drawn.
+	gridMinZoom = 8
+)
+
+// viewport is the zoom and pan state shared by the image views, so that
+// views showing different images stay aligned.
+type viewport struct {
+	// fit scales the image to the view and centres it; otherwise scale
+	// (units per image pixel) and origin (the image point at the top-left
+	// corner of the view) are used.
+	fit    bool
+	scale  float32
+	origin fyne.Position
+	// frame is the image space fitted to the views.
+	frame func() image.Rectangle
+	// grid draws the pixel borders at high zoom.
+	grid bool
+
+	// size and pixScale (device pixels per unit) of the last rendered
+	// view, for the zoom presets.
+	size     fyne.Size
+	pixScale float32
+
+	views []*imageView
+	// onZoom is told the zoom (device pixels per image pixel) after changes.
+	onZoom   func(zoom float32)
+	lastZoom float32
+
+	// onHover receives the image point and colour under the mouse, with
+	// ok false once it leaves the image; onTap receives clicked points.
+	onHover func(pt image.Point, c color.NRGBA, ok bool)
+	onTap   func(pt image.Point)
+	// pins are image points marked on every view.
+	pins []image.Point
+}
+
+func newViewport(frame func() image.Rectangle) *viewport {
+	return &viewport{fit: true, frame: frame}
+}
+
+// transform returns the scale and origin for a view of the given size.
+func (vp *viewport) transform(size fyne.Size) (float32, fyne.Position) {
+	frame := vp.frame()
+	if !vp.fit || frame.Empty() || size.IsZero() {
+		return vp.scale, vp.origin
+	}
+
+	scale := min(size.Width/float32(frame.Dx()), size.Height/float32(frame.Dy()))
+	centerX := float32(frame.Min.X) + float32(frame.Dx())/2
+	centerY := float32(frame.Min.Y) + float32(frame.Dy())/2
+
+	return scale, fyne.NewPos(centerX-size.Width/2/scale, centerY-size.Height/2/scale)
+}
+
+// zoomAt multiplies the scale by factor keeping the image point under at
+// in place.
+func (vp *viewport) zoomAt(factor float32, at fyne.Position, size fyne.Size) {
+	scale, origin := vp.transform(size)
+	if scale == 0 {
+		return
+	}
+
+	newScale := min(max(scale*factor, minZoom), maxZoom)
+
+	vp.origin = fyne.NewPos(origin.X+at.X/scale-at.X/newScale, origin.Y+at.Y/scale-at.Y/newScale)
+	vp.scale = newScale
+	vp.fit = false
+	vp.refresh()
+}
+
+// pan moves the image by delta units.
+func (vp *viewport) pan(delta fyne.Delta, size fyne.Size) {
+	scale, origin := vp.transform(size)
+	if scale == 0 {
+		return
+	}
+
+	vp.origin = fyne.NewPos(origin.X-delta.DX/scale, origin.Y-delta.DY/scale)
+	vp.scale = scale
+	vp.fit = false
+	vp.refresh()
+}
+
+func (vp *viewport) setFit() {
+	vp.fit = true
+	vp.refresh()
+}
+
+// setZoom shows image pixels at zoom device pixels each, keeping the centre
+// of the view in place; 1 is 100%.
+func (vp *viewport) setZoom(zoom float32) {
+	if vp.size.IsZero() || vp.pixScale == 0 {
+		return
+	}
+	scale, _ := vp.transform(vp.size)
+	if scale == 0 {
+		return
+	}
+	center := fyne.NewPos(vp.size.Width/2, vp.size.Height/2)
+	vp.zoomAt(zoom/vp.pixScale/scale, center, vp.size)
+}
+
+func (vp *viewport) setPins(pins []image.Point) {
+	vp.pins = pins
+	vp.refresh()
+}
+
+// imagePoint converts a position in a view to the image pixel under it.
+func (vp *viewport) imagePoint(pos fyne.Position, size fyne.Size) (image.Point, bool) {
+	scale, origin := vp.transform(size)
+	if scale == 0 {
+		return image.Point{}, false
+	}
+	x := math.Floor(float64(origin.X + pos.X/scale))
+	y := math.Floor(float64(origin.Y + pos.Y/scale))
+	return image.Pt(int(x), int(y)), true
+}
+
+func (vp *viewport) setGrid(grid bool) {
+	vp.grid = grid
+	vp.refresh()
+}
+
+// rendered records the geometry of a view that has just been drawn and
+// reports a changed zoom, which in fit mode follows the view size.
+func (vp *viewport) rendered(size fyne.Size, pixScale float32) {
+	vp.size, vp.pixScale = size, pixScale
+
+	scale, _ := vp.transform(size)
+	zoom := scale * pixScale
+	if zoom == vp.lastZoom || vp.onZoom == nil {
+		return
+	}
+	vp.lastZoom = zoom
+	fyne.Do(func() {
+		vp.onZoom(zoom)
+	})
+}
+
+func (vp *viewport) refresh() {
+	for _, view := range vp.views {
+		view.raster.Refresh()
+	}
+}
+
+// layer supplies an image to a view together with the rectangle of image
+// space it covers; a preview proxy covers the full-size image it stands for.
+type layer func() (image.Image, image.Rectangle)
+
+// pixelAt returns the colour of the layer at pt, a point of image space.
+func pixelAt(l layer, pt image.Point) (color.NRGBA, bool) {
+	src, frame := l()
+	if src == nil || !pt.In(frame) {
+		return color.NRGBA{}, false
+	}
+
+	bounds := src.Bounds()
+	x := bounds.Min.X + (pt.X-frame.Min.X)*bounds.Dx()/frame.Dx()
+	y := bounds.Min.Y + (pt.Y-frame.Min.Y)*bounds.Dy()/frame.Dy()
+
+	return color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA), true
+}
+
+// rgbaCache keeps the RGBA conversion of the last image drawn, since
+// decoded files are usually YCbCr or paletted.
+type rgbaCache struct {
+	src  image.Image
+	rgba *image.RGBA
+}
+
+func (c *rgbaCache) get(src image.Image) *image.RGBA {
+	if src != c.src {
+		c.src, c.rgba = src, engine.ToRGBA(src)
+	}
+	return c.rgba
+}
+
+// imageView draws a layer through a shared viewport. With a before layer
+// it becomes a split view: before is shown left of a draggable divider and
+// after right of it.
+type imageView struct {
+	widget.BaseWidget
+
+	vp            *viewport
+	before, after layer
+	// divider is the split position as a fraction of the width.
+	divider float32
+
+	raster                   *canvas.Raster
+	beforeCache, afterCache  rgbaCache
+	draggingDivider, panning bool
+	hoverDivider             bool
+}
+
+func newImageView(vp *viewport, before, after layer) *imageView {
+	view := &imageView{vp: vp, before: before, after: after, divider: 0.5}
+	view.raster = canvas.NewRaster(view.generate)
+	view.ExtendBaseWidget(view)
+
+	vp.views = append(vp.views, view)
+	return view
+}
+
+func (v *imageView) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(v.raster)
+}
+
+func (v *imageView) Scrolled(event *fyne.ScrollEvent) {
+	factor := float32(wheelZoomStep)
+	if event.Scrolled.DY < 0 {
+		factor = 1 / factor
+	}
+	v.vp.zoomAt(factor, event.Position, v.Size())
+}
+
+func (v *imageView) Dragged(event *fyne.DragEvent) {
+	if !v.draggingDivider && !v.panning {
+		start := event.Position.Subtract(event.Dragged)
+		v.draggingDivider = v.nearDivider(start)
+		v.panning = !v.draggingDivider
+	}
+
+	if v.draggingDivider {
+		v.divider = min(max(event.Position.X/v.Size().Width, 0), 1)
+		v.raster.Refresh()
+		return
+	}
+
+	v.vp.pan(event.Dragged, v.Size())
+}
+
+func (v *imageView) DragEnd() {
+	v.draggingDivider, v.panning = false, false
+}
+
+func (v *imageView) nearDivider(pos fyne.Position) bool {
+	return v.before != nil && abs32(pos.X-v.divider*v.Size().Width) <= dividerGrab
+}
+
+func (v *imageView) MouseIn(event *desktop.MouseEvent) {
+	v.MouseMoved(event)
+}
+
+func (v *imageView) MouseMoved(event *desktop.MouseEvent) {
+	v.hoverDivider = v.nearDivider(event.Position)
+
+	if v.vp.onHover == nil {
+		return
+	}
+	pt, ok := v.vp.imagePoint(event.Position, v.Size())
+	var c color.NRGBA
+	if ok {
+		c, ok = pixelAt(v.layerAt(event.Position), pt)
+	}
+	v.vp.onHover(pt, c, ok)
+}
+
+func (v *imageView) MouseOut() {
+	v.hoverDivider = false
+	if v.vp.onHover != nil {
+		v.vp.onHover(image.Point{}, color.NRGBA{}, false)
+	}
+}
+
+func (v *imageView) Tapped(event *fyne.PointEvent) {
+	if v.vp.onTap == nil {
+		return
+	}
+	if pt, ok := v.vp.imagePoint(event.Position, v.Size()); ok && pt.In(v.vp.frame()) {
+		v.vp.onTap(pt)
+	}
+}
+
+// layerAt returns the layer shown at pos, which depends on the divider in
+// a split view.
+func (v *imageView) layerAt(pos fyne.Position) layer {
+	if v.before != nil && pos.X < v.divider*v.Size().Width {
+		return v.before
+	}
+	return v.after
+}
+
+func (v *imageView) Cursor() desktop.Cursor {
+	if v.hoverDivider || v.draggingDivider {
+		return desktop.HResizeCursor
+	}
+	return desktop.DefaultCursor
+}
+
+func abs32(value float32) float32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// columns maps every device column (or row) of the view to a pixel of src
+// covering frame, or -1 where the view is outside the image. phase is the
+// position sampled inside the device pixel, 0.5 being its centre.
+func columns(n int, origin, step, phase float32, frameMin, frameSize, srcMin, srcSize int) []int {
+	res := make([]int, n)
+	for i := range res {
+		pos := float64(origin + (float32(i)+phase)*step)
+		offset := int(math.Floor((pos - float64(frameMin)) * float64(srcSize) / float64(frameSize)))
+		if pos < float64(frameMin) || offset >= srcSize {
+			res[i] = -1
+			continue
+		}
+		res[i] = srcMin + offset
+	}
+	return res
+}
+
+// sampler holds the column and row lookup tables of one layer. When the
+// image is zoomed out it also keeps two extra taps per axis, averaged to
+// avoid the aliasing of nearest-neighbour sampling.
+type sampler struct {
+	rgba       *image.RGBA
+	cols, rows []int
+
+	smooth           bool
+	subCols, subRows [2][]int
+}
+
+func newSampler(cache *rgbaCache, l layer, w, h int, scale float32, origin fyne.Position, pixScale float32) *sampler {
+	src, frame := l()
+	if src == nil || frame.Empty() {
+		return nil
+	}
+
+	rgba := cache.get(src)
+	bounds := rgba.Bounds()
+	step := 1 / (pixScale * scale)
+
+	s := &sampler{
+		rgba: rgba,
+		cols: columns(w, origin.X, step, 0.5, frame.Min.X, frame.Dx(), bounds.Min.X, bounds.Dx()),
+		rows: columns(h, origin.Y, step, 0.5, frame.Min.Y, frame.Dy(), bounds.Min.Y, bounds.Dy()),
+		// The source may be a downscaled proxy, hence the size ratio.
+		smooth: step*float32(bounds.Dx())/float32(frame.Dx()) > 1,
+	}
+
+	if s.smooth {
+		for i, phase := range []float32{0.25, 0.75} {
+			s.subCols[i] = columns(w, origin.X, step, phase, frame.Min.X, frame.Dx(), bounds.Min.X, bounds.Dx())
+			s.subRows[i] = columns(h, origin.Y, step, phase, frame.Min.Y, frame.Dy(), bounds.Min.Y, bounds.Dy())
+		}
+	}
+
+	return s
+}
+
+// pixel writes the colour of device pixel (px, py) to out, reporting false
+// outside the image.
+func (s *sampler) pixel(px, py int, out []uint8) bool {
+	x, y := s.cols[px], s.rows[py]
+	if x < 0 || y < 0 {
+		return false
+	}
+
+	if s.smooth {
+		var sum [4]int
+		n := 0
+		for _, cols := range s.subCols {
+			for _, rows := range s.subRows {
+				if cols[px] < 0 || rows[py] < 0 {
+					continue
+				}
+				i := s.rgba.PixOffset(cols[px], rows[py])
+				for c := range sum {
+					sum[c] += int(s.rgba.Pix[i+c])
+				}
+				n++
+			}
+		}
+		if n == 4 {
+			for c := range sum {
+				out[c] = uint8(sum[c] / n)
+			}
+			return true
+		}
+	}
+
+	i := s.rgba.PixOffset(x, y)
+	copy(out, s.rgba.Pix[i:i+4])
+	return true
+}
+
+var (
+	dividerColor = []uint8{255, 255, 255, 255}
+	gridColor    = []uint8{64, 64, 64, 255}
+	pinColor     = []uint8{255, 0, 255, 255}
+)
+
+// pinRadius is the arm length of the pin markers in device pixels.
+const pinRadius = 6
+
+// generate renders the visible part of the layers at the raster's device
+// resolution: nearest-neighbour when zoomed in, averaged when zoomed out.
+func (v *imageView) generate(w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	size := v.Size()
+	if size.Width == 0 {
+		return dst
+	}
+
+	pixScale := float32(w) / size.Width
+	scale, origin := v.vp.transform(size)
+	if scale == 0 {
+		return dst
+	}
+	v.vp.rendered(size, pixScale)
+
+	after := newSampler(&v.afterCache, v.after, w, h, scale, origin, pixScale)
+	var before *sampler
+	if v.before != nil {
+		before = newSampler(&v.beforeCache, v.before, w, h, scale, origin, pixScale)
+	}
+
+	dividerX := int(v.divider * float32(w))
+	grid := after != nil && v.vp.grid && scale*pixScale >= gridMinZoom
+
+	for py := range h {
+		row := dst.Pix[py*dst.Stride:]
+
+		if grid && py > 0 && after.rows[py] != after.rows[py-1] {
+			for px := range w {
+				copy(row[px*4:px*4+4], gridColor)
+			}
+			continue
+		}
+
+		for px := range w {
+			s := after
+			if before != nil && px < dividerX {
+				s = before
+			}
+			if s == nil {
+				continue
+			}
+
+			if grid && px > 0 && after.cols[px] != after.cols[px-1] {
+				copy(row[px*4:px*4+4], gridColor)
+				continue
+			}
+
+			s.pixel(px, py, row[px*4:px*4+4])
+		}
+
+		if before != nil && dividerX < w {
+			copy(row[dividerX*4:dividerX*4+4], dividerColor)
+		}
+	}
+
+	for _, pin := range v.vp.pins {
+		drawPin(dst, image.Pt(
+			int((float32(pin.X)+0.5-origin.X)*scale*pixScale),
+			int((float32(pin.Y)+0.5-origin.Y)*scale*pixScale),
+		))
+	}
+
+	return dst
+}
+
+// drawPin draws a cross centred at the device pixel at.
+func drawPin(dst *image.RGBA, at image.Point) {
+	for d := -pinRadius; d <= pinRadius; d++ {
+		for _, pt := range []image.Point{at.Add(image.Pt(d, 0)), at.Add(image.Pt(0, d))} {
+			if pt.In(dst.Rect) {
+				i := dst.PixOffset(pt.X, pt.Y)
+				copy(dst.Pix[i:i+4], pinColor)
+			}
+		}
+	}
+}
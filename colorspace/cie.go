@@ -0,0 +1,90 @@
+package colorspace
+
+import "math"
+
+// D65 is the reference white of sRGB in CIE XYZ, with Y = 1.
+var D65 = [3]float64{0.95047, 1, 1.08883}
+
+// linearize removes the sRGB transfer curve from an 8-bit component.
+func linearize(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// delinearize applies the sRGB transfer curve and converts to 8 bits.
+func delinearize(v float64) uint8 {
+	v = clamp01(v)
+	if v <= 0.0031308 {
+		return toByte(v * 12.92)
+	}
+	return toByte(1.055*math.Pow(v, 1/2.4) - 0.055)
+}
+
+// RGBToXYZ converts an sRGB colour to CIE XYZ under D65, with Y in [0, 1].
+func RGBToXYZ(r, g, b uint8) (x, y, z float64) {
+	rl, gl, bl := linearize(r), linearize(g), linearize(b)
+
+	x = 0.4124564*rl + 0.3575761*gl + 0.1804375*bl
+	y = 0.2126729*rl + 0.7151522*gl + 0.0721750*bl
+	z = 0.0193339*rl + 0.1191920*gl + 0.9503041*bl
+	return x, y, z
+}
+
+// XYZToRGB is the inverse of RGBToXYZ, clamping colours outside the sRGB
+// gamut.
+func XYZToRGB(x, y, z float64) (r, g, b uint8) {
+	rl := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	gl := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	bl := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return delinearize(rl), delinearize(gl), delinearize(bl)
+}
+
+const (
+	labEpsilon = 216. / 24389
+	labKappa   = 24389. / 27
+)
+
+func labF(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return (labKappa*t + 16) / 116
+}
+
+func labFInverse(f float64) float64 {
+	if cube := f * f * f; cube > labEpsilon {
+		return cube
+	}
+	return (116*f - 16) / labKappa
+}
+
+// XYZToLab converts CIE XYZ to CIE L*a*b* relative to D65. L is in
+// [0, 100]; a and b are roughly within [-128, 127] for sRGB colours.
+func XYZToLab(x, y, z float64) (l, a, b float64) {
+	fx := labF(x / D65[0])
+	fy := labF(y / D65[1])
+	fz := labF(z / D65[2])
+
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// LabToXYZ is the inverse of XYZToLab.
+func LabToXYZ(l, a, b float64) (x, y, z float64) {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+
+	return labFInverse(fx) * D65[0], labFInverse(fy) * D65[1], labFInverse(fz) * D65[2]
+}
+
+func RGBToLab(r, g, b uint8) (l, a, bb float64) {
+	return XYZToLab(RGBToXYZ(r, g, b))
+}
+
+// LabToRGB converts back to sRGB, clamping colours outside its gamut.
+func LabToRGB(l, a, b float64) (r, g, bb uint8) {
+	return XYZToRGB(LabToXYZ(l, a, b))
+}
@@ -0,0 +1,80 @@
+// Package colorspace converts 8-bit RGB colours to and from other colour
+// models.
+package colorspace
+
+import "math"
+
+// RGBToHSV returns hue in degrees [0, 360) and saturation and value in
+// [0, 1]. Grays have hue 0.
+func RGBToHSV(r, g, b uint8) (h, s, v float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+
+	maxC := max(rf, gf, bf)
+	minC := min(rf, gf, bf)
+	delta := maxC - minC
+
+	v = maxC
+	if maxC > 0 {
+		s = delta / maxC
+	}
+	if delta == 0 {
+		return 0, s, v
+	}
+
+	switch maxC {
+	case rf:
+		h = math.Mod((gf-bf)/delta, 6)
+	case gf:
+		h = (bf-rf)/delta + 2
+	default:
+		h = (rf-gf)/delta + 4
+	}
+
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+// HSVToRGB is the inverse of RGBToHSV; h is taken modulo 360 and s and v
+// are clamped to [0, 1].
+func HSVToRGB(h, s, v float64) (r, g, b uint8) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s = clamp01(s)
+	v = clamp01(v)
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+
+	return toByte(rf + m), toByte(gf + m), toByte(bf + m)
+}
+
+func clamp01(value float64) float64 {
+	return min(max(value, 0), 1)
+}
+
+// toByte converts a [0, 1] component to 0..255 with rounding.
+func toByte(value float64) uint8 {
+	return uint8(math.Round(clamp01(value) * 255))
+}
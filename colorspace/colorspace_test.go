@@ -0,0 +1,111 @@
+package colorspace
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRGBToHSV(t *testing.T) {
+	tests := []struct {
+		r, g, b uint8
+		h, s, v float64
+	}{
+		{0, 0, 0, 0, 0, 0},
+		{255, 255, 255, 0, 0, 1},
+		{255, 0, 0, 0, 1, 1},
+		{0, 255, 0, 120, 1, 1},
+		{0, 0, 255, 240, 1, 1},
+		{255, 0, 255, 300, 1, 1},
+		{128, 64, 64, 0, 0.5, 128. / 255},
+	}
+
+	for _, tc := range tests {
+		h, s, v := RGBToHSV(tc.r, tc.g, tc.b)
+		if math.Abs(h-tc.h) > 1e-9 || math.Abs(s-tc.s) > 1e-9 || math.Abs(v-tc.v) > 1e-9 {
+			t.Errorf("RGBToHSV(%d, %d, %d) = %v, %v, %v, want %v, %v, %v", tc.r, tc.g, tc.b, h, s, v, tc.h, tc.s, tc.v)
+		}
+	}
+}
+
+func TestHSVRoundTrip(t *testing.T) {
+	for r := 0; r < 256; r += 15 {
+		for g := 0; g < 256; g += 15 {
+			for b := 0; b < 256; b += 15 {
+				gotR, gotG, gotB := HSVToRGB(RGBToHSV(uint8(r), uint8(g), uint8(b)))
+				if int(gotR) != r || int(gotG) != g || int(gotB) != b {
+					t.Fatalf("HSV round trip of (%d, %d, %d) gave (%d, %d, %d)", r, g, b, gotR, gotG, gotB)
+				}
+			}
+		}
+	}
+}
+
+// roundTrip checks that convert followed by invert gives back every
+// sampled RGB colour.
+func roundTrip(t *testing.T, name string, convert func(r, g, b uint8) (float64, float64, float64), invert func(x, y, z float64) (uint8, uint8, uint8)) {
+	t.Helper()
+
+	for r := 0; r < 256; r += 15 {
+		for g := 0; g < 256; g += 15 {
+			for b := 0; b < 256; b += 15 {
+				gotR, gotG, gotB := invert(convert(uint8(r), uint8(g), uint8(b)))
+				if int(gotR) != r || int(gotG) != g || int(gotB) != b {
+					t.Fatalf("%s round trip of (%d, %d, %d) gave (%d, %d, %d)", name, r, g, b, gotR, gotG, gotB)
+				}
+			}
+		}
+	}
+}
+
+func TestRoundTrips(t *testing.T) {
+	roundTrip(t, "HSL", RGBToHSL, HSLToRGB)
+	roundTrip(t, "YCbCr", RGBToYCbCr, YCbCrToRGB)
+	roundTrip(t, "XYZ", RGBToXYZ, XYZToRGB)
+	roundTrip(t, "Lab", RGBToLab, LabToRGB)
+}
+
+func TestRGBToHSL(t *testing.T) {
+	tests := []struct {
+		r, g, b uint8
+		h, s, l float64
+	}{
+		{0, 0, 0, 0, 0, 0},
+		{255, 255, 255, 0, 0, 1},
+		{255, 0, 0, 0, 1, 0.5},
+		{0, 0, 128, 240, 1, 64. / 255},
+		{191, 64, 64, 0, 127. / 255, 0.5},
+	}
+
+	for _, tc := range tests {
+		h, s, l := RGBToHSL(tc.r, tc.g, tc.b)
+		if math.Abs(h-tc.h) > 1e-9 || math.Abs(s-tc.s) > 1e-9 || math.Abs(l-tc.l) > 1e-9 {
+			t.Errorf("RGBToHSL(%d, %d, %d) = %v, %v, %v, want %v, %v, %v", tc.r, tc.g, tc.b, h, s, l, tc.h, tc.s, tc.l)
+		}
+	}
+}
+
+func TestRGBToLab(t *testing.T) {
+	tests := []struct {
+		r, g, b  uint8
+		l, a, bb float64
+	}{
+		{255, 255, 255, 100, 0, 0},
+		{0, 0, 0, 0, 0, 0},
+		{255, 0, 0, 53.24, 80.09, 67.20},
+		{0, 0, 255, 32.30, 79.19, -107.86},
+	}
+
+	for _, tc := range tests {
+		l, a, bb := RGBToLab(tc.r, tc.g, tc.b)
+		if math.Abs(l-tc.l) > 0.01 || math.Abs(a-tc.a) > 0.01 || math.Abs(bb-tc.bb) > 0.01 {
+			t.Errorf("RGBToLab(%d, %d, %d) = %.2f, %.2f, %.2f, want %v, %v, %v", tc.r, tc.g, tc.b, l, a, bb, tc.l, tc.a, tc.bb)
+		}
+	}
+}
+
+func TestRGBToYCbCr(t *testing.T) {
+	y, cb, cr := RGBToYCbCr(128, 128, 128)
+	if math.Abs(y-128) > 1e-9 || math.Abs(cb-128) > 1e-9 || math.Abs(cr-128) > 1e-9 {
+		t.Errorf("gray gave %v, %v, %v", y, cb, cr)
+	}
+}
@@ -0,0 +1,38 @@
+package colorspace
+
+import "math"
+
+// RGBToHSL returns hue in degrees [0, 360) and saturation and lightness in
+// [0, 1]. Grays have hue and saturation 0.
+func RGBToHSL(r, g, b uint8) (h, s, l float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+
+	maxC := max(rf, gf, bf)
+	minC := min(rf, gf, bf)
+	delta := maxC - minC
+
+	l = (maxC + minC) / 2
+	if delta == 0 {
+		return 0, 0, l
+	}
+
+	s = delta / (1 - math.Abs(2*l-1))
+	h, _, _ = RGBToHSV(r, g, b)
+	return h, clamp01(s), l
+}
+
+// HSLToRGB is the inverse of RGBToHSL; h is taken modulo 360 and s and l
+// are clamped to [0, 1].
+func HSLToRGB(h, s, l float64) (r, g, b uint8) {
+	s = clamp01(s)
+	l = clamp01(l)
+
+	// HSL and HSV share the hue; only the way chroma and brightness are
+	// measured differs.
+	v := l + s*min(l, 1-l)
+	var sv float64
+	if v > 0 {
+		sv = 2 * (1 - l/v)
+	}
+	return HSVToRGB(h, sv, v)
+}
@@ -0,0 +1,24 @@
+package colorspace
+
+// RGBToYCbCr converts to full-range BT.601 YCbCr as used by JPEG, without
+// rounding: y, cb and cr are in [0, 255] and neutral colours have
+// cb = cr = 128.
+func RGBToYCbCr(r, g, b uint8) (y, cb, cr float64) {
+	rf, gf, bf := float64(r), float64(g), float64(b)
+
+	y = 0.299*rf + 0.587*gf + 0.114*bf
+	cb = 128 - 0.168736*rf - 0.331264*gf + 0.5*bf
+	cr = 128 + 0.5*rf - 0.418688*gf - 0.081312*bf
+	return y, cb, cr
+}
+
+// YCbCrToRGB is the inverse of RGBToYCbCr, clamping colours outside the
+// RGB cube.
+func YCbCrToRGB(y, cb, cr float64) (r, g, b uint8) {
+	cb -= 128
+	cr -= 128
+
+	return toByte((y + 1.402*cr) / 255),
+		toByte((y - 0.344136*cb - 0.714136*cr) / 255),
+		toByte((y + 1.772*cb) / 255)
+}
@@ -0,0 +1,222 @@
+// Package engine runs per-row image work in parallel bands over *image.RGBA
+// pixel buffers.
+package engine
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// bandsPerWorker splits the image finer than the worker count so that
+// uneven rows (e.g. near borders) do not leave goroutines idle; minBands
+// keeps progress reports and cancellation checks frequent on few CPUs.
+const (
+	bandsPerWorker = 4
+	minBands       = 64
+)
+
+var workers atomic.Int64
+
+func init() {
+	workers.Store(int64(runtime.NumCPU()))
+}
+
+// SetWorkers changes the number of goroutines used by Rows and returns the
+// previous value. n < 1 is treated as 1.
+func SetWorkers(n int) int {
+	return int(workers.Swap(int64(max(n, 1))))
+}
+
+func Workers() int {
+	return int(workers.Load())
+}
+
+// ProgressFunc receives the completed fraction (0..1) of an operation. It
+// may be called from several goroutines at once.
+type ProgressFunc func(fraction float64)
+
+type progressKey struct{}
+
+// WithProgress attaches fn to ctx so that Rows reports to it.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// SubProgress maps the progress reported under the returned context into
+// the [from, to] part of the parent's range, for multi-pass operations.
+func SubProgress(ctx context.Context, from, to float64) context.Context {
+	parent, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if !ok {
+		return ctx
+	}
+	return WithProgress(ctx, func(fraction float64) {
+		parent(from + (to-from)*fraction)
+	})
+}
+
+func report(ctx context.Context, fraction float64) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(fraction)
+	}
+}
+
+// Rows calls fn for consecutive bands [y0, y1) covering bounds, spreading
+// the bands over the worker goroutines. fn must only write rows of its band.
+// Rows reports progress to ctx and stops handing out bands once ctx is
+// done, returning its error.
+func Rows(ctx context.Context, bounds image.Rectangle, fn func(y0, y1 int)) error {
+	height := bounds.Dy()
+	if height <= 0 {
+		return ctx.Err()
+	}
+
+	n := Workers()
+	bandHeight := max(height/max(n*bandsPerWorker, minBands), 1)
+	bands := (height + bandHeight - 1) / bandHeight
+
+	var next, doneRows atomic.Int64
+	var wg sync.WaitGroup
+
+	for range min(n, bands) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				band := int(next.Add(1) - 1)
+				if band >= bands {
+					return
+				}
+				y0 := bounds.Min.Y + band*bandHeight
+				y1 := min(y0+bandHeight, bounds.Max.Y)
+				fn(y0, y1)
+
+				report(ctx, float64(doneRows.Add(int64(y1-y0)))/float64(height))
+			}
+		}()
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+// SerialRows calls fn for every row of bounds from top to bottom on the
+// calling goroutine, for algorithms where a row depends on the ones above
+// it such as error diffusion. It reports progress and cancellation like
+// Rows.
+func SerialRows(ctx context.Context, bounds image.Rectangle, fn func(y int)) error {
+	height := bounds.Dy()
+	step := max(height/minBands, 1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fn(y)
+		if done := y - bounds.Min.Y + 1; done%step == 0 || done == height {
+			report(ctx, float64(done)/float64(height))
+		}
+	}
+	return ctx.Err()
+}
+
+// rows is Rows for quick preparatory passes that are neither reported nor
+// cancelled.
+func rows(bounds image.Rectangle, fn func(y0, y1 int)) {
+	Rows(context.Background(), bounds, fn)
+}
+
+// ToRGBA returns src itself when it already is an *image.RGBA and a
+// converted copy otherwise. Callers must not modify the result.
+func ToRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	rows(bounds, func(y0, y1 int) {
+		band := image.Rect(bounds.Min.X, y0, bounds.Max.X, y1)
+		draw.Draw(dst, band, src, band.Min, draw.Src)
+	})
+	return dst
+}
+
+// Thumbnail scales src down so that neither side exceeds maxSide, for quick
+// previews. Images that already fit are returned as they are.
+func Thumbnail(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	side := max(bounds.Dx(), bounds.Dy())
+	if side <= maxSide {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(bounds.Dx()*maxSide/side, 1), max(bounds.Dy()*maxSide/side, 1)))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// Map applies fn to every pixel of src and returns the result. Pixels are
+// 8-bit alpha-premultiplied RGBA, as stored in image.RGBA.
+func Map(ctx context.Context, src image.Image, fn func(r, g, b, a uint8) (uint8, uint8, uint8, uint8)) (*image.RGBA, error) {
+	in := ToRGBA(src)
+	bounds := in.Bounds()
+	out := image.NewRGBA(bounds)
+
+	err := Rows(ctx, bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			i := in.PixOffset(bounds.Min.X, y)
+			o := out.PixOffset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, i, o = x+1, i+4, o+4 {
+				out.Pix[o], out.Pix[o+1], out.Pix[o+2], out.Pix[o+3] = fn(in.Pix[i], in.Pix[i+1], in.Pix[i+2], in.Pix[i+3])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Plane is a single float channel of an image, e.g. its luminance.
+type Plane struct {
+	Rect   image.Rectangle
+	Stride int
+	Pix    []float64
+}
+
+func NewPlane(bounds image.Rectangle) *Plane {
+	return &Plane{Rect: bounds, Stride: bounds.Dx(), Pix: make([]float64, bounds.Dx()*bounds.Dy())}
+}
+
+func (p *Plane) Offset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x - p.Rect.Min.X)
+}
+
+func (p *Plane) At(x, y int) float64 {
+	return p.Pix[p.Offset(x, y)]
+}
+
+// PlaneOf builds a plane by evaluating fn on every pixel of src.
+func PlaneOf(src image.Image, fn func(r, g, b, a uint8) float64) *Plane {
+	in := ToRGBA(src)
+	bounds := in.Bounds()
+	plane := NewPlane(bounds)
+
+	rows(bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			i := in.PixOffset(bounds.Min.X, y)
+			o := plane.Offset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, i, o = x+1, i+4, o+1 {
+				plane.Pix[o] = fn(in.Pix[i], in.Pix[i+1], in.Pix[i+2], in.Pix[i+3])
+			}
+		}
+	})
+
+	return plane
+}
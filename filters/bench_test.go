@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"testing"
+
+	"photoshop/engine"
+)
+
+// benchmarkImage returns a deterministic Full HD test image.
+func benchmarkImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for y := range 1080 {
+		for x := range 1920 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return img
+}
+
+// BenchmarkFilters compares every filter on one worker with all CPUs.
+func BenchmarkFilters(b *testing.B) {
+	src := benchmarkImage()
+
+	workerCounts := []int{1}
+	if runtime.NumCPU() > 1 {
+		workerCounts = append(workerCounts, runtime.NumCPU())
+	}
+
+	for _, f := range All() {
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("%s/workers=%d", f.Name(), workers), func(b *testing.B) {
+				previous := engine.SetWorkers(workers)
+				defer engine.SetWorkers(previous)
+
+				for b.Loop() {
+					if _, err := f.Apply(context.Background(), src, testParams[f.Name()]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/engine"
+)
+
+type CannyParams struct {
+	// Sigma is the standard deviation of the Gaussian smoothing; 0 skips it.
+	Sigma float64
+	// Low and High are the hysteresis thresholds on the Sobel gradient
+	// magnitude, in the gray levels the Sobel filter shows.
+	Low, High float64
+	// Auto derives Low and High from the median brightness instead.
+	Auto   bool
+	Border BorderMode
+}
+
+const (
+	maxCannySigma = 5
+	// autoCannySpread places the automatic thresholds this fraction below
+	// and above the median.
+	autoCannySpread = 0.33
+)
+
+func (p CannyParams) validate() error {
+	if p.Sigma < 0 || p.Sigma > maxCannySigma {
+		return fmt.Errorf("filters: canny sigma %g out of range [0, %d]", p.Sigma, maxCannySigma)
+	}
+	if p.Auto {
+		return nil
+	}
+	if p.Low < 0 || p.High > 255 || p.Low > p.High {
+		return fmt.Errorf("filters: canny thresholds %g and %g must satisfy 0 <= low <= high <= 255", p.Low, p.High)
+	}
+	return nil
+}
+
+// CannyThresholds returns the hysteresis thresholds params lead to on src.
+func CannyThresholds(src image.Image, params CannyParams) (low, high float64) {
+	if !params.Auto {
+		return params.Low, params.High
+	}
+	median := float64(Stats(Histogram(src)).Median)
+	return max(0, (1-autoCannySpread)*median), min(255, (1+autoCannySpread)*median)
+}
+
+// gaussKernels returns the separable row and column kernels of the
+// binomial Gaussian whose variance is closest to sigma².
+func gaussKernels(sigma float64) (row, column Kernel) {
+	// The binomial row of size n has variance (n-1)/4.
+	size := 2*int(math.Round(2*sigma*sigma)) + 1
+	weights := pascalRow(max(size, 3))
+
+	row = Kernel{Width: len(weights), Height: 1, Data: weights, Normalize: true}
+	column = Kernel{Width: 1, Height: len(weights), Data: weights, Normalize: true}
+	return row, column
+}
+
+// Canny finds thin edges: it smooths the luminance, takes the Sobel
+// gradient, keeps only its local maxima across the edge and traces edges
+// from pixels above High through pixels above Low.
+func Canny(ctx context.Context, src image.Image, params CannyParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	low, high := CannyThresholds(src, params)
+
+	smooth := lumaPlane(src)
+	if params.Sigma > 0 {
+		row, column := gaussKernels(params.Sigma)
+		planes, err := convolvePlanes(engine.SubProgress(ctx, 0, 0.2), smooth, []Kernel{row}, params.Border)
+		if err != nil {
+			return nil, err
+		}
+		planes, err = convolvePlanes(engine.SubProgress(ctx, 0.2, 0.4), planes[0], []Kernel{column}, params.Border)
+		if err != nil {
+			return nil, err
+		}
+		smooth = planes[0]
+	}
+
+	gradients, err := convolvePlanes(engine.SubProgress(ctx, 0.4, 0.7), smooth, sobelKernels, params.Border)
+	if err != nil {
+		return nil, err
+	}
+	gx, gy := gradients[0], gradients[1]
+	bounds := gx.Rect
+
+	magnitude := engine.NewPlane(bounds)
+	for i := range magnitude.Pix {
+		magnitude.Pix[i] = math.Hypot(gx.Pix[i], gy.Pix[i])
+	}
+
+	at := func(x, y int) float64 {
+		if !(image.Point{x, y}).In(bounds) {
+			return 0
+		}
+		return magnitude.At(x, y)
+	}
+
+	// Non-maximum suppression: a pixel stays a candidate only if it is a
+	// maximum along its gradient direction. Of two equal neighbours only the
+	// second one is kept, so plateaus stay one pixel wide.
+	const (
+		none uint8 = iota
+		weak
+		strong
+	)
+	marks := make([]uint8, len(magnitude.Pix))
+
+	err = engine.Rows(engine.SubProgress(ctx, 0.7, 0.9), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				o := magnitude.Offset(x, y)
+				value := magnitude.Pix[o]
+				if value < low || value == 0 {
+					continue
+				}
+
+				// gy grows upwards (see sobelKernels), image rows downwards.
+				angle := math.Atan2(-gy.Pix[o], gx.Pix[o]) * 180 / math.Pi
+				if angle < 0 {
+					angle += 180
+				}
+
+				var dx, dy int
+				switch {
+				case angle < 22.5 || angle >= 157.5:
+					dx, dy = 1, 0
+				case angle < 67.5:
+					dx, dy = 1, 1
+				case angle < 112.5:
+					dx, dy = 0, 1
+				default:
+					dx, dy = -1, 1
+				}
+
+				if value < at(x+dx, y+dy) || value <= at(x-dx, y-dy) {
+					continue
+				}
+
+				marks[o] = weak
+				if value >= high {
+					marks[o] = strong
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Hysteresis: grow the strong pixels through 8-connected weak ones.
+	var stack []image.Point
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if marks[magnitude.Offset(x, y)] == strong {
+				stack = append(stack, image.Pt(x, y))
+			}
+		}
+	}
+	for len(stack) > 0 {
+		pt := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				next := pt.Add(image.Pt(dx, dy))
+				if !next.In(bounds) {
+					continue
+				}
+				if o := magnitude.Offset(next.X, next.Y); marks[o] == weak {
+					marks[o] = strong
+					stack = append(stack, next)
+				}
+			}
+		}
+	}
+
+	dst := image.NewRGBA(bounds)
+	err = engine.Rows(engine.SubProgress(ctx, 0.9, 1), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				var value uint8
+				if marks[magnitude.Offset(x, y)] == strong {
+					value = 255
+				}
+				setGray(dst, x, y, value)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+var cannyThresholdNames = []string{"Manual", "Auto"}
+
+func cannyParams(p Params) CannyParams {
+	return CannyParams{
+		Sigma:  p.Float("sigma"),
+		Low:    p.Float("low"),
+		High:   p.Float("high"),
+		Auto:   p.Index("thresholds", cannyThresholdNames) == 1,
+		Border: borderOf(p),
+	}
+}
+
+func init() {
+	Register(&reportingFilter{
+		basicFilter: basicFilter{
+			name: "canny", title: "Canny", category: EdgeDetection,
+			params: []Param{
+				{Name: "sigma", Label: "Sigma", Kind: ParamFloat, Min: 0, Max: maxCannySigma, Step: 0.1, Default: 1.4, Slider: true},
+				{Name: "thresholds", Label: "Thresholds (Auto follows the median)", Kind: ParamChoice, Choices: cannyThresholdNames, Default: "Manual"},
+				{Name: "low", Label: "Low threshold", Kind: ParamFloat, Min: 0, Max: 255, Step: 1, Default: 40., Slider: true},
+				{Name: "high", Label: "High threshold", Kind: ParamFloat, Min: 0, Max: 255, Step: 1, Default: 100., Slider: true},
+				borderParam,
+			},
+			apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+				return Canny(ctx, src, cannyParams(p))
+			},
+		},
+		report: func(_ context.Context, src image.Image, p Params) (string, error) {
+			params := cannyParams(p)
+			if err := params.validate(); err != nil {
+				return "", err
+			}
+			low, high := CannyThresholds(src, params)
+			return fmt.Sprintf("Thresholds: %.0f to %.0f", low, high), nil
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestCannyThinEdge(t *testing.T) {
+	// A vertical step has to come out as a single line, one pixel wide.
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := range 16 {
+		for x := range 32 {
+			v := uint8(20)
+			if x >= 16 {
+				v = 220
+			}
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	got, err := Canny(context.Background(), img, CannyParams{Sigma: 1.4, Low: 40, High: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := engine.ToRGBA(got)
+
+	for y := range 16 {
+		var edges []int
+		for x := range 32 {
+			if out.RGBAAt(x, y).R == 255 {
+				edges = append(edges, x)
+			}
+		}
+		if len(edges) != 1 || edges[0] < 15 || edges[0] > 16 {
+			t.Fatalf("row %d: edge pixels at %v, want one next to x=16", y, edges)
+		}
+	}
+}
+
+func TestCannyAutoThresholds(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 100
+	}
+
+	low, high := CannyThresholds(img, CannyParams{Auto: true})
+	if low != 67 || high != 133 {
+		t.Errorf("thresholds %v and %v, want 67 and 133", low, high)
+	}
+
+	if err := (CannyParams{Sigma: 1, Low: 50, High: 20}).validate(); err == nil {
+		t.Error("low above high accepted")
+	}
+}
@@ -0,0 +1,221 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"photoshop/colorspace"
+	"photoshop/engine"
+)
+
+type HueRange int
+
+const (
+	HueReds HueRange = iota
+	HueYellows
+	HueGreens
+	HueCyans
+	HueBlues
+	HueMagentas
+)
+
+var hueRangeNames = []string{"Reds", "Yellows", "Greens", "Cyans", "Blues", "Magentas"}
+
+// HueRanges lists the ranges in the order of their centre hues, 60° apart
+// starting at red.
+func HueRanges() []HueRange {
+	return []HueRange{HueReds, HueYellows, HueGreens, HueCyans, HueBlues, HueMagentas}
+}
+
+func (r HueRange) String() string {
+	return hueRangeNames[r]
+}
+
+// weight tells how much a hue belongs to the range: fully within 15° of the
+// centre, fading out linearly until 45°, so that the weights of
+// neighbouring ranges add up to 1 between them.
+func (r HueRange) weight(hue float64) float64 {
+	distance := math.Abs(math.Mod(hue-float64(r)*60+540, 360) - 180)
+	return min(max((45-distance)/30, 0), 1)
+}
+
+type HueSaturationParams struct {
+	// Hue rotates every hue by this many degrees, or picks the tint in
+	// colorize mode.
+	Hue float64
+	// Saturation and Lightness are in [-100, 100]; 0 changes nothing.
+	Saturation float64
+	Lightness  float64
+	// RangeSaturation adds to Saturation for the colours of each range.
+	RangeSaturation [6]float64
+	// Colorize replaces every hue with Hue; Saturation then sets the
+	// strength of the tint, from none at -100 to full at 100.
+	Colorize bool
+}
+
+func checkPercent(name string, value float64) error {
+	if value < -100 || value > 100 {
+		return fmt.Errorf("filters: %s %g out of range [-100, 100]", name, value)
+	}
+	return nil
+}
+
+func (p HueSaturationParams) validate() error {
+	if p.Hue < -180 || p.Hue > 360 {
+		return fmt.Errorf("filters: hue %g out of range [-180, 360]", p.Hue)
+	}
+	if err := checkPercent("saturation", p.Saturation); err != nil {
+		return err
+	}
+	if err := checkPercent("lightness", p.Lightness); err != nil {
+		return err
+	}
+	for _, hueRange := range HueRanges() {
+		if err := checkPercent(hueRange.String()+" saturation", p.RangeSaturation[hueRange]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustLightness moves l towards black for negative amounts and towards
+// white for positive ones; amount is in [-1, 1].
+func adjustLightness(l, amount float64) float64 {
+	if amount < 0 {
+		return l * (1 + amount)
+	}
+	return l + (1-l)*amount
+}
+
+// HueSaturation adjusts colours in HSL: hue rotation, saturation overall
+// and per hue range, lightness, or a single-hue colorize.
+func HueSaturation(ctx context.Context, src image.Image, params HueSaturationParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	lightness := params.Lightness / 100
+
+	return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+		h, s, l := colorspace.RGBToHSL(r, g, b)
+
+		if params.Colorize {
+			h = params.Hue
+			s = (params.Saturation + 100) / 200
+		} else {
+			amount := params.Saturation
+			if s > 0 {
+				for _, hueRange := range HueRanges() {
+					amount += hueRange.weight(h) * params.RangeSaturation[hueRange]
+				}
+			}
+			h += params.Hue
+			s *= 1 + min(max(amount, -100), 100)/100
+		}
+
+		r, g, b = colorspace.HSLToRGB(h, s, adjustLightness(l, lightness))
+		return r, g, b, a
+	})
+}
+
+type LabAdjustParams struct {
+	// Lightness is added to L* (0..100).
+	Lightness float64
+	// A and B shift the green-magenta and blue-yellow axes.
+	A, B float64
+	// Chroma scales the distance from gray, in [-100, 100] percent.
+	Chroma float64
+}
+
+func (p LabAdjustParams) validate() error {
+	for _, check := range []struct {
+		name  string
+		value float64
+	}{{"lightness", p.Lightness}, {"a*", p.A}, {"b*", p.B}, {"chroma", p.Chroma}} {
+		if err := checkPercent(check.name, check.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LabAdjust edits colours in CIE L*a*b*, where lightness changes leave the
+// perceived hue and colourfulness alone.
+func LabAdjust(ctx context.Context, src image.Image, params LabAdjustParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	chroma := 1 + params.Chroma/100
+
+	return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+		l, la, lb := colorspace.RGBToLab(r, g, b)
+
+		l = min(max(l+params.Lightness, 0), 100)
+		la = la*chroma + params.A
+		lb = lb*chroma + params.B
+
+		r, g, b = colorspace.LabToRGB(l, la, lb)
+		return r, g, b, a
+	})
+}
+
+var colorizeNames = []string{"Off", "On"}
+
+func init() {
+	params := []Param{
+		{Name: "hue", Label: "Hue", Kind: ParamFloat, Min: -180, Max: 180, Step: 1, Default: 0., Slider: true},
+		{Name: "saturation", Label: "Saturation", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+		{Name: "lightness", Label: "Lightness", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+	}
+	for _, hueRange := range HueRanges() {
+		params = append(params, Param{
+			Name: hueRangeParam(hueRange), Label: hueRange.String() + " saturation",
+			Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true,
+		})
+	}
+	params = append(params, Param{Name: "colorize", Label: "Colorize", Kind: ParamChoice, Choices: colorizeNames, Default: "Off"})
+
+	Register(&basicFilter{
+		name: "hue-saturation", title: "Hue/Saturation", category: Adjustments,
+		params: params,
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			hs := HueSaturationParams{
+				Hue:        p.Float("hue"),
+				Saturation: p.Float("saturation"),
+				Lightness:  p.Float("lightness"),
+				Colorize:   p.Index("colorize", colorizeNames) == 1,
+			}
+			for _, hueRange := range HueRanges() {
+				hs.RangeSaturation[hueRange] = p.Float(hueRangeParam(hueRange))
+			}
+			return HueSaturation(ctx, src, hs)
+		},
+	})
+	Register(&basicFilter{
+		name: "lab-adjust", title: "Lab adjust", category: Adjustments,
+		params: []Param{
+			{Name: "lightness", Label: "Lightness (L*)", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+			{Name: "a", Label: "Green - magenta (a*)", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+			{Name: "b", Label: "Blue - yellow (b*)", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+			{Name: "chroma", Label: "Chroma %", Kind: ParamFloat, Min: -100, Max: 100, Step: 1, Default: 0., Slider: true},
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return LabAdjust(ctx, src, LabAdjustParams{
+				Lightness: p.Float("lightness"),
+				A:         p.Float("a"),
+				B:         p.Float("b"),
+				Chroma:    p.Float("chroma"),
+			})
+		},
+	})
+}
+
+// hueRangeParam is the name of the saturation parameter of a range, e.g.
+// "reds".
+func hueRangeParam(r HueRange) string {
+	return strings.ToLower(r.String())
+}
@@ -0,0 +1,75 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestHueRangeWeights(t *testing.T) {
+	for hue := 0.; hue < 360; hue += 7.5 {
+		sum := 0.
+		for _, hueRange := range HueRanges() {
+			sum += hueRange.weight(hue)
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("weights at %v° add up to %v", hue, sum)
+		}
+	}
+	if w := HueReds.weight(350); w != 1 {
+		t.Errorf("350° belongs to the reds by %v", w)
+	}
+}
+
+func TestColorAdjustmentsIdentity(t *testing.T) {
+	src := loadPNG(t, "testdata/fixtures/orig.png")
+	ctx := context.Background()
+
+	hs, err := HueSaturation(ctx, src, HueSaturationParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := compareImages(hs, src); diff != "" {
+		t.Errorf("hue/saturation: %s", diff)
+	}
+
+	lab, err := LabAdjust(ctx, src, LabAdjustParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := compareImages(lab, src); diff != "" {
+		t.Errorf("lab: %s", diff)
+	}
+}
+
+func TestHueSaturationRange(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.SetRGBA(0, 0, color.RGBA{200, 40, 40, 255})
+	img.SetRGBA(1, 0, color.RGBA{40, 40, 200, 255})
+	img.SetRGBA(2, 0, color.RGBA{90, 90, 90, 255})
+
+	params := HueSaturationParams{}
+	params.RangeSaturation[HueReds] = -100
+	got, err := HueSaturation(context.Background(), img, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := engine.ToRGBA(got)
+
+	if c := out.RGBAAt(0, 0); c.R != c.G || c.G != c.B {
+		t.Errorf("red became %v, want gray", c)
+	}
+	if c := out.RGBAAt(1, 0); c != img.RGBAAt(1, 0) {
+		t.Errorf("blue became %v", c)
+	}
+
+	params = HueSaturationParams{Hue: 240, Saturation: 100, Colorize: true}
+	got, _ = HueSaturation(context.Background(), img, params)
+	if c := engine.ToRGBA(got).RGBAAt(2, 0); c.B <= c.R || c.R != c.G {
+		t.Errorf("colorized gray became %v, want blue", c)
+	}
+}
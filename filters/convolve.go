@@ -0,0 +1,413 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+
+	"photoshop/engine"
+)
+
+// BorderMode decides which pixels the kernel sees past the image edge.
+type BorderMode int
+
+const (
+	// BorderClamp repeats the edge pixel: aaa|abcd|ddd.
+	BorderClamp BorderMode = iota
+	// BorderReflect mirrors the image including the edge: cba|abcd|dcb.
+	BorderReflect
+	// BorderWrap tiles the image: bcd|abcd|abc.
+	BorderWrap
+	// BorderConstant fills the outside with ConvolveOptions.Constant.
+	BorderConstant
+	// BorderCrop only keeps pixels whose whole window is inside the image,
+	// so the result is smaller than the source.
+	BorderCrop
+)
+
+var borderModeNames = []string{"Clamp", "Reflect", "Wrap", "Constant", "Crop"}
+
+func BorderModeNames() []string {
+	return slices.Clone(borderModeNames)
+}
+
+func (m BorderMode) String() string {
+	if m < 0 || int(m) >= len(borderModeNames) {
+		return fmt.Sprintf("BorderMode(%d)", int(m))
+	}
+	return borderModeNames[m]
+}
+
+// borderParam is the schema entry shared by every neighbourhood filter.
+// Filters leave ConvolveOptions.Constant at zero, so Constant pads with
+// black.
+var borderParam = Param{
+	Name: "border", Label: "Border (Constant pads with black)", Kind: ParamChoice, Choices: borderModeNames, Default: "Clamp",
+}
+
+func borderOf(p Params) BorderMode {
+	return BorderMode(p.Index("border", borderModeNames))
+}
+
+// resolve maps the coordinate v into [lo, hi). ok is false when v lies
+// outside and the mode fills it with a constant.
+func (m BorderMode) resolve(v, lo, hi int) (int, bool) {
+	if v >= lo && v < hi {
+		return v, true
+	}
+
+	n := hi - lo
+	t := v - lo
+
+	switch m {
+	case BorderReflect:
+		t = ((t % (2 * n)) + 2*n) % (2 * n)
+		if t >= n {
+			t = 2*n - 1 - t
+		}
+	case BorderWrap:
+		t = ((t % n) + n) % n
+	case BorderConstant:
+		return 0, false
+	default:
+		t = min(max(t, 0), n-1)
+	}
+
+	return lo + t, true
+}
+
+// Kernel is a rectangular convolution mask stored row by row. The anchor,
+// i.e. the tap aligned with the output pixel, is ((Width-1)/2, (Height-1)/2).
+type Kernel struct {
+	Width  int       `json:"width"`
+	Height int       `json:"height"`
+	Data   []float64 `json:"data"`
+	// Divisor divides the weighted sum; zero means 1, or the sum of the
+	// weights when Normalize is set.
+	Divisor   float64 `json:"divisor,omitempty"`
+	Normalize bool    `json:"normalize,omitempty"`
+	// Bias is added after the division, e.g. 128 for embossing.
+	Bias float64 `json:"bias,omitempty"`
+}
+
+// NewKernel builds a kernel from rows of equal length.
+func NewKernel(rows [][]float64) Kernel {
+	k := Kernel{Height: len(rows)}
+	if len(rows) > 0 {
+		k.Width = len(rows[0])
+	}
+	for _, row := range rows {
+		k.Data = append(k.Data, row...)
+	}
+	return k
+}
+
+func kernel3x3(rows [3][3]float64) Kernel {
+	return NewKernel([][]float64{rows[0][:], rows[1][:], rows[2][:]})
+}
+
+func (k Kernel) At(x, y int) float64 {
+	return k.Data[y*k.Width+x]
+}
+
+func (k Kernel) anchor() image.Point {
+	return image.Pt((k.Width-1)/2, (k.Height-1)/2)
+}
+
+func (k Kernel) Validate() error {
+	if k.Width < 1 || k.Height < 1 {
+		return fmt.Errorf("filters: kernel size %dx%d must be positive", k.Width, k.Height)
+	}
+	if len(k.Data) != k.Width*k.Height {
+		return fmt.Errorf("filters: kernel %dx%d needs %d values, got %d", k.Width, k.Height, k.Width*k.Height, len(k.Data))
+	}
+	if k.divisor() == 0 {
+		return fmt.Errorf("filters: kernel weights sum to zero and cannot be normalized")
+	}
+	return nil
+}
+
+func (k Kernel) divisor() float64 {
+	if k.Divisor != 0 {
+		return k.Divisor
+	}
+	if !k.Normalize {
+		return 1
+	}
+	var sum float64
+	for _, weight := range k.Data {
+		sum += weight
+	}
+	return sum
+}
+
+type ConvolveOptions struct {
+	Border BorderMode
+	// Constant is the colour outside the image for BorderConstant.
+	Constant color.RGBA
+	// Gray convolves the luminance and writes it to all three channels.
+	Gray bool
+	// Abs takes the magnitude of the divided sum before adding the bias.
+	Abs bool
+}
+
+// window precomputes which source pixels every output pixel reads for a
+// kernel of a given size, so that the inner loops need no border checks.
+type window struct {
+	src    image.Rectangle
+	out    image.Rectangle
+	width  int
+	height int
+	anchor image.Point
+	border BorderMode
+	// cols holds width source columns per output column, -1 for constant.
+	cols []int
+}
+
+func newWindow(src image.Rectangle, width, height int, anchor image.Point, border BorderMode) (*window, error) {
+	if border < BorderClamp || border > BorderCrop {
+		return nil, fmt.Errorf("filters: unknown border mode %d", int(border))
+	}
+
+	out := src
+	if border == BorderCrop {
+		out = image.Rectangle{
+			Min: src.Min.Add(anchor),
+			Max: src.Max.Sub(image.Pt(width-1-anchor.X, height-1-anchor.Y)),
+		}
+		if out.Empty() {
+			return nil, fmt.Errorf("filters: image %dx%d is smaller than the %dx%d window", src.Dx(), src.Dy(), width, height)
+		}
+	}
+
+	w := &window{src: src, out: out, width: width, height: height, anchor: anchor, border: border}
+
+	w.cols = make([]int, out.Dx()*width)
+	for x := out.Min.X; x < out.Max.X; x++ {
+		for kx := range width {
+			sx, ok := border.resolve(x+kx-anchor.X, src.Min.X, src.Max.X)
+			if !ok {
+				sx = -1
+			}
+			w.cols[(x-out.Min.X)*width+kx] = sx
+		}
+	}
+
+	return w, nil
+}
+
+// rows fills idx with the source rows read for output row y.
+func (w *window) rows(y int, idx []int) {
+	for ky := range w.height {
+		sy, ok := w.border.resolve(y+ky-w.anchor.Y, w.src.Min.Y, w.src.Max.Y)
+		if !ok {
+			sy = -1
+		}
+		idx[ky] = sy
+	}
+}
+
+// columns returns the source columns read for output column x.
+func (w *window) columns(x int) []int {
+	i := (x - w.out.Min.X) * w.width
+	return w.cols[i : i+w.width]
+}
+
+// Convolve applies k to src. The result is clamped to 0..255 and opaque.
+func Convolve(ctx context.Context, src image.Image, k Kernel, opts ConvolveOptions) (image.Image, error) {
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+
+	in := engine.ToRGBA(src)
+
+	win, err := newWindow(in.Bounds(), k.Width, k.Height, k.anchor(), opts.Border)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(win.out)
+
+	var luma *engine.Plane
+	if opts.Gray {
+		luma = lumaPlane(in)
+	}
+
+	divisor := k.divisor()
+	constR, constG, constB := float64(opts.Constant.R), float64(opts.Constant.G), float64(opts.Constant.B)
+	if opts.Gray {
+		constR = Luminance(uint32(opts.Constant.R), uint32(opts.Constant.G), uint32(opts.Constant.B))
+	}
+
+	post := func(sum float64) uint8 {
+		value := sum / divisor
+		if opts.Abs {
+			value = math.Abs(value)
+		}
+		return uint8(checkForLimit(value + k.Bias))
+	}
+
+	err = engine.Rows(ctx, win.out, func(y0, y1 int) {
+		rowIdx := make([]int, k.Height)
+
+		for y := y0; y < y1; y++ {
+			win.rows(y, rowIdx)
+
+			for x := win.out.Min.X; x < win.out.Max.X; x++ {
+				colIdx := win.columns(x)
+
+				var sumR, sumG, sumB float64
+
+				for ky, sy := range rowIdx {
+					for kx, sx := range colIdx {
+						factor := k.Data[ky*k.Width+kx]
+						if factor == 0 {
+							continue
+						}
+
+						switch {
+						case sy < 0 || sx < 0:
+							sumR += constR * factor
+							sumG += constG * factor
+							sumB += constB * factor
+						case opts.Gray:
+							sumR += luma.At(sx, sy) * factor
+						default:
+							i := in.PixOffset(sx, sy)
+							sumR += float64(in.Pix[i]) * factor
+							sumG += float64(in.Pix[i+1]) * factor
+							sumB += float64(in.Pix[i+2]) * factor
+						}
+					}
+				}
+
+				if opts.Gray {
+					sumG, sumB = sumR, sumR
+				}
+
+				o := dst.PixOffset(x, y)
+				dst.Pix[o] = post(sumR)
+				dst.Pix[o+1] = post(sumG)
+				dst.Pix[o+2] = post(sumB)
+				dst.Pix[o+3] = 255
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+// convolvePlanes convolves p with kernels of the same size, returning one
+// plane of sum/divisor + bias per kernel. Constant border taps read 0.
+func convolvePlanes(ctx context.Context, p *engine.Plane, kernels []Kernel, border BorderMode) ([]*engine.Plane, error) {
+	for _, kernel := range kernels {
+		if err := kernel.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	first := kernels[0]
+	win, err := newWindow(p.Rect, first.Width, first.Height, first.anchor(), border)
+	if err != nil {
+		return nil, err
+	}
+
+	planes := make([]*engine.Plane, len(kernels))
+	divisors := make([]float64, len(kernels))
+	for i, kernel := range kernels {
+		planes[i] = engine.NewPlane(win.out)
+		divisors[i] = kernel.divisor()
+	}
+
+	err = engine.Rows(ctx, win.out, func(y0, y1 int) {
+		rowIdx := make([]int, first.Height)
+		taps := make([]float64, first.Width*first.Height)
+
+		for y := y0; y < y1; y++ {
+			win.rows(y, rowIdx)
+
+			for x := win.out.Min.X; x < win.out.Max.X; x++ {
+				colIdx := win.columns(x)
+
+				for ky, sy := range rowIdx {
+					for kx, sx := range colIdx {
+						if sy < 0 || sx < 0 {
+							taps[ky*first.Width+kx] = 0
+						} else {
+							taps[ky*first.Width+kx] = p.At(sx, sy)
+						}
+					}
+				}
+
+				for i, kernel := range kernels {
+					var sum float64
+					for j, tap := range taps {
+						sum += tap * kernel.Data[j]
+					}
+					planes[i].Pix[planes[i].Offset(x, y)] = sum/divisors[i] + kernel.Bias
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return planes, nil
+}
+
+// gradientImage convolves the luminance of src with several kernels of the
+// same size and writes value(responses) as a gray level.
+func gradientImage(ctx context.Context, src image.Image, kernels []Kernel, border BorderMode, value func(responses []float64) float64) (image.Image, error) {
+	return gradientColorImage(ctx, src, kernels, border, func(responses []float64) (uint8, uint8, uint8) {
+		gray := uint8(checkForLimit(value(responses)))
+		return gray, gray, gray
+	})
+}
+
+// gradientColorImage is gradientImage for outputs that need colour.
+func gradientColorImage(ctx context.Context, src image.Image, kernels []Kernel, border BorderMode, pixel func(responses []float64) (r, g, b uint8)) (image.Image, error) {
+	planes, err := convolvePlanes(engine.SubProgress(ctx, 0, 0.9), lumaPlane(src), kernels, border)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := planes[0].Rect
+	colorImg := image.NewRGBA(bounds)
+
+	err = engine.Rows(engine.SubProgress(ctx, 0.9, 1), bounds, func(y0, y1 int) {
+		responses := make([]float64, len(planes))
+
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				o := planes[0].Offset(x, y)
+				for i, plane := range planes {
+					responses[i] = plane.Pix[o]
+				}
+
+				o = colorImg.PixOffset(x, y)
+				colorImg.Pix[o], colorImg.Pix[o+1], colorImg.Pix[o+2] = pixel(responses)
+				colorImg.Pix[o+3] = 255
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return colorImg, nil
+}
+
+func setGray(dst *image.RGBA, x, y int, value uint8) {
+	o := dst.PixOffset(x, y)
+	dst.Pix[o] = value
+	dst.Pix[o+1] = value
+	dst.Pix[o+2] = value
+	dst.Pix[o+3] = 255
+}
@@ -0,0 +1,140 @@
+package filters
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestBorderResolve(t *testing.T) {
+	// Columns -3..6 of a 4-pixel wide image.
+	tests := []struct {
+		mode BorderMode
+		want []int
+	}{
+		{BorderClamp, []int{0, 0, 0, 0, 1, 2, 3, 3, 3, 3}},
+		{BorderReflect, []int{2, 1, 0, 0, 1, 2, 3, 3, 2, 1}},
+		{BorderWrap, []int{1, 2, 3, 0, 1, 2, 3, 0, 1, 2}},
+		{BorderConstant, []int{-1, -1, -1, 0, 1, 2, 3, -1, -1, -1}},
+	}
+
+	for _, tc := range tests {
+		var got []int
+		for v := -3; v <= 6; v++ {
+			resolved, ok := tc.mode.resolve(v, 0, 4)
+			if !ok {
+				resolved = -1
+			}
+			got = append(got, resolved)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: got %v, want %v", tc.mode, got, tc.want)
+		}
+	}
+}
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for y := range 5 {
+		for x := range 7 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 30), uint8(y * 50), uint8(x * y * 5), 255})
+		}
+	}
+	return img
+}
+
+func TestConvolveIdentity(t *testing.T) {
+	src := testImage()
+	identity := NewKernel([][]float64{{0, 0, 0}, {0, 1, 0}, {0, 0, 0}})
+
+	for _, border := range []BorderMode{BorderClamp, BorderReflect, BorderWrap, BorderConstant} {
+		got, err := Convolve(context.Background(), src, identity, ConvolveOptions{Border: border})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := compareImages(got, src); diff != "" {
+			t.Errorf("%v: %s", border, diff)
+		}
+	}
+}
+
+func TestConvolveCrop(t *testing.T) {
+	kernel := Kernel{Width: 5, Height: 3, Data: make([]float64, 15), Normalize: false}
+	kernel.Data[7] = 1
+
+	got, err := Convolve(context.Background(), testImage(), kernel, ConvolveOptions{Border: BorderCrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(2, 1, 5, 4); got.Bounds() != want {
+		t.Errorf("bounds %v, want %v", got.Bounds(), want)
+	}
+
+	if _, err := Convolve(context.Background(), testImage(), GaussKernel(7), ConvolveOptions{Border: BorderCrop}); err == nil {
+		t.Error("cropping a 7x5 image with a 7x7 kernel succeeded")
+	}
+}
+
+// GaussBlur spreads an impulse over the whole size x size window centred on
+// it; the pre-convolution version only used a round(size/3) radius.
+func TestGaussBlurImpulse(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 255
+	}
+	src.SetRGBA(4, 4, color.RGBA{255, 255, 255, 255})
+
+	got, err := GaussBlur(context.Background(), src, GaussBlurParams{Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := engine.ToRGBA(got)
+
+	for d := 1; d <= 3; d++ {
+		left, right := out.RGBAAt(4-d, 4).R, out.RGBAAt(4+d, 4).R
+		up, down := out.RGBAAt(4, 4-d).R, out.RGBAAt(4, 4+d).R
+		if left != right || up != down || left != up {
+			t.Errorf("offset %d: left %d, right %d, up %d, down %d, want equal", d, left, right, up, down)
+		}
+		if inside := d <= 2; inside != (left > 0) {
+			t.Errorf("offset %d: value %d, want non-zero only inside the 5x5 window", d, left)
+		}
+	}
+	if centre, next := out.RGBAAt(4, 4).R, out.RGBAAt(5, 4).R; centre <= next {
+		t.Errorf("centre %d not above its neighbour %d", centre, next)
+	}
+}
+
+func TestKernelValidate(t *testing.T) {
+	bad := []Kernel{
+		{},
+		{Width: 3, Height: 3, Data: make([]float64, 8)},
+		{Width: 3, Height: 1, Data: []float64{1, -2, 1}, Normalize: true},
+	}
+	for _, kernel := range bad {
+		if kernel.Validate() == nil {
+			t.Errorf("%+v: Validate succeeded", kernel)
+		}
+	}
+}
+
+func TestKernelPresetJSON(t *testing.T) {
+	for _, preset := range KernelPresets() {
+		var buf bytes.Buffer
+		if err := WriteKernelPreset(&buf, preset); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadKernelPreset(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, preset) {
+			t.Errorf("%s: read back %+v", preset.Name, got)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/engine"
+)
+
+type DitherMethod int
+
+const (
+	DitherNone DitherMethod = iota
+	DitherFloydSteinberg
+	DitherAtkinson
+	DitherJarvisJudiceNinke
+	DitherStucki
+	DitherBayer2
+	DitherBayer4
+	DitherBayer8
+)
+
+var ditherMethodNames = []string{
+	"None", "Floyd-Steinberg", "Atkinson", "Jarvis-Judice-Ninke", "Stucki", "Bayer 2x2", "Bayer 4x4", "Bayer 8x8",
+}
+
+func (m DitherMethod) String() string {
+	if m < 0 || int(m) >= len(ditherMethodNames) {
+		return fmt.Sprintf("DitherMethod(%d)", int(m))
+	}
+	return ditherMethodNames[m]
+}
+
+func (m DitherMethod) validate() error {
+	if m < DitherNone || m > DitherBayer8 {
+		return fmt.Errorf("filters: unknown dither method %d", m)
+	}
+	return nil
+}
+
+// bayerSize returns the side of the threshold matrix of an ordered
+// method, or 0 for error diffusion and DitherNone.
+func (m DitherMethod) bayerSize() int {
+	switch m {
+	case DitherBayer2:
+		return 2
+	case DitherBayer4:
+		return 4
+	case DitherBayer8:
+		return 8
+	}
+	return 0
+}
+
+// diffusionTap passes weight of the quantization error to the pixel dx to
+// the right (in scan direction) and dy rows down.
+type diffusionTap struct {
+	dx, dy int
+	weight float64
+}
+
+func diffusionTaps(divisor float64, rows ...[]float64) []diffusionTap {
+	var taps []diffusionTap
+	for dy, row := range rows {
+		// The first row starts right of the current pixel, the others are
+		// centred on it.
+		first := -len(row) / 2
+		if dy == 0 {
+			first = 1
+		}
+		for i, weight := range row {
+			if weight != 0 {
+				taps = append(taps, diffusionTap{dx: first + i, dy: dy, weight: weight / divisor})
+			}
+		}
+	}
+	return taps
+}
+
+var diffusionKernels = map[DitherMethod][]diffusionTap{
+	DitherFloydSteinberg: diffusionTaps(16, []float64{7}, []float64{3, 5, 1}),
+	// Atkinson passes on only 6/8 of the error, which keeps contrast at
+	// the cost of losing detail in highlights and shadows.
+	DitherAtkinson:          diffusionTaps(8, []float64{1, 1}, []float64{1, 1, 1}, []float64{1}),
+	DitherJarvisJudiceNinke: diffusionTaps(48, []float64{7, 5}, []float64{3, 5, 7, 5, 3}, []float64{1, 3, 5, 3, 1}),
+	DitherStucki:            diffusionTaps(42, []float64{8, 4}, []float64{2, 4, 8, 4, 2}, []float64{1, 2, 4, 2, 1}),
+}
+
+// bayerMatrix returns the size x size ordered dithering thresholds, in
+// [-0.5, 0.5), built recursively from the 2x2 matrix.
+func bayerMatrix(size int) [][]float64 {
+	index := [][]int{{0}}
+	for n := 1; n < size; n *= 2 {
+		next := make([][]int, 2*n)
+		for y := range next {
+			next[y] = make([]int, 2*n)
+			for x := range next[y] {
+				next[y][x] = 4*index[y%n][x%n] + [2][2]int{{0, 2}, {3, 1}}[y/n][x/n]
+			}
+		}
+		index = next
+	}
+
+	matrix := make([][]float64, size)
+	for y := range matrix {
+		matrix[y] = make([]float64, size)
+		for x := range matrix[y] {
+			matrix[y][x] = (float64(index[y][x])+0.5)/float64(size*size) - 0.5
+		}
+	}
+	return matrix
+}
+
+// quantizer returns the representable colour closest to c, whose
+// components may lie outside [0, 255] after an ordered dither offset.
+type quantizer func(c [3]float64) [3]uint8
+
+// dither reduces src to the colours of quantize using method, keeping
+// alpha. spread is the typical distance between neighbouring colours, which
+// ordered methods scale their thresholds to.
+func dither(ctx context.Context, src image.Image, method DitherMethod, spread float64, quantize quantizer) (image.Image, error) {
+	in := engine.ToRGBA(src)
+	bounds := in.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	pixel := func(i int) [3]float64 {
+		return [3]float64{float64(in.Pix[i]), float64(in.Pix[i+1]), float64(in.Pix[i+2])}
+	}
+	store := func(i int, c [3]uint8) {
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = c[0], c[1], c[2], in.Pix[i+3]
+	}
+
+	if size := method.bayerSize(); size > 0 || method == DitherNone {
+		matrix := [][]float64{{0}}
+		if size > 0 {
+			matrix = bayerMatrix(size)
+		}
+		err := engine.Rows(ctx, bounds, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				// The matrix is anchored at the image origin, which may be negative.
+				row := matrix[(y-bounds.Min.Y)%len(matrix)]
+				i := in.PixOffset(bounds.Min.X, y)
+				for x := bounds.Min.X; x < bounds.Max.X; x, i = x+1, i+4 {
+					c := pixel(i)
+					offset := row[(x-bounds.Min.X)%len(row)] * spread
+					store(i, quantize([3]float64{c[0] + offset, c[1] + offset, c[2] + offset}))
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dst, nil
+	}
+
+	taps := diffusionKernels[method]
+	width := bounds.Dx()
+
+	// errs holds the pending error of the current row and the two below,
+	// padded by two pixels on both sides for the widest kernels.
+	const pad = 2
+	var errs [3][][3]float64
+	for k := range errs {
+		errs[k] = make([][3]float64, width+2*pad)
+	}
+
+	err := engine.SerialRows(ctx, bounds, func(y int) {
+		// Serpentine scanning runs odd rows right to left, which avoids
+		// the diagonal streaks of always pushing the error one way.
+		dir, start := 1, 0
+		if (y-bounds.Min.Y)%2 == 1 {
+			dir, start = -1, width-1
+		}
+
+		for n, col := 0, start; n < width; n, col = n+1, col+dir {
+			i := in.PixOffset(bounds.Min.X+col, y)
+			c := pixel(i)
+			pending := &errs[0][col+pad]
+			for k := range c {
+				// Clamping keeps the error of unreachable values, e.g. below
+				// the darkest level, from piling up across the image.
+				c[k] = checkForLimit(c[k] + pending[k])
+			}
+
+			out := quantize(c)
+			store(i, out)
+
+			for k := range c {
+				e := c[k] - float64(out[k])
+				for _, tap := range taps {
+					errs[tap.dy][col+pad+tap.dx*dir][k] += e * tap.weight
+				}
+			}
+		}
+
+		errs[0], errs[1], errs[2] = errs[1], errs[2], errs[0]
+		clear(errs[2])
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// levelQuantizer maps every channel to the nearest of levels.
+func levelQuantizer(levels []uint8) quantizer {
+	var nearest [256]uint8
+	for v := range nearest {
+		best := levels[0]
+		for _, level := range levels {
+			if math.Abs(float64(level)-float64(v)) < math.Abs(float64(best)-float64(v)) {
+				best = level
+			}
+		}
+		nearest[v] = best
+	}
+
+	return func(c [3]float64) [3]uint8 {
+		var out [3]uint8
+		for k, v := range c {
+			out[k] = nearest[int(math.Round(checkForLimit(v)))]
+		}
+		return out
+	}
+}
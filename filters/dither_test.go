@@ -0,0 +1,106 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestBayerMatrix(t *testing.T) {
+	want := [][]float64{{-0.375, 0.125}, {0.375, -0.125}}
+	for y, row := range bayerMatrix(2) {
+		for x, v := range row {
+			if v != want[y][x] {
+				t.Fatalf("bayerMatrix(2) = %v, want %v", bayerMatrix(2), want)
+			}
+		}
+	}
+
+	// Every threshold of the 8x8 matrix is used exactly once.
+	seen := map[float64]bool{}
+	for _, row := range bayerMatrix(8) {
+		for _, v := range row {
+			seen[v] = true
+		}
+	}
+	if len(seen) != 64 {
+		t.Errorf("bayerMatrix(8) has %d distinct thresholds, want 64", len(seen))
+	}
+}
+
+func TestDiffusionWeights(t *testing.T) {
+	for method, want := range map[DitherMethod]float64{
+		DitherFloydSteinberg:    1,
+		DitherAtkinson:          0.75,
+		DitherJarvisJudiceNinke: 1,
+		DitherStucki:            1,
+	} {
+		sum := 0.
+		for _, tap := range diffusionKernels[method] {
+			sum += tap.weight
+		}
+		if math.Abs(sum-want) > 1e-9 {
+			t.Errorf("%v passes on %v of the error, want %v", method, sum, want)
+		}
+	}
+}
+
+func TestOneBitDitherKeepsBrightness(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 64, 64, 64, 255
+	}
+
+	for _, method := range []DitherMethod{DitherFloydSteinberg, DitherJarvisJudiceNinke, DitherStucki, DitherBayer4, DitherBayer8} {
+		res, err := Quantization(context.Background(), img, QuantizationParams{Dither: method, OneBit: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		white := 0
+		for y := range 64 {
+			for x := range 64 {
+				switch res.At(x, y) {
+				case color.RGBA{255, 255, 255, 255}:
+					white++
+				case color.RGBA{0, 0, 0, 255}:
+				default:
+					t.Fatalf("%v: pixel (%d, %d) = %v is neither black nor white", method, x, y, res.At(x, y))
+				}
+			}
+		}
+		// A quarter of the pixels, give or take the error lost over the
+		// edges.
+		if math.Abs(float64(white)/4096-0.25) > 0.02 {
+			t.Errorf("%v: %d of 4096 pixels white, want about a quarter", method, white)
+		}
+	}
+}
+
+func TestQuantizationLUTRejectsDithering(t *testing.T) {
+	if _, err := QuantizationLUT(QuantizationParams{Quants: 4, Dither: DitherAtkinson}); err == nil {
+		t.Error("QuantizationLUT built a table for a dithered quantization")
+	}
+}
+
+func TestDitherOffsetBounds(t *testing.T) {
+	img := image.NewRGBA(image.Rect(-3, -3, 5, 5))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 100, 150, 200, 255
+	}
+
+	for method := range DitherMethod(len(ditherMethodNames)) {
+		res, err := Quantization(context.Background(), img, QuantizationParams{Quants: 4, Dither: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Bounds() != img.Bounds() {
+			t.Errorf("%v: bounds %v, want %v", method, res.Bounds(), img.Bounds())
+		}
+		if _, err := ReducePalette(context.Background(), img, PaletteParams{Colors: 4, Dither: method}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/colorspace"
+)
+
+type ShiftDirection int
+
+const (
+	ShiftVertical ShiftDirection = iota
+	ShiftHorizontal
+	ShiftDiagonal
+)
+
+type EmbossDirection int
+
+const (
+	EmbossIn EmbossDirection = iota
+	EmbossOut
+)
+
+type ShiftEdgeParams struct {
+	Direction ShiftDirection
+	Border    BorderMode
+}
+
+type EmbossingParams struct {
+	Direction EmbossDirection
+	Border    BorderMode
+}
+
+// EdgeParams configures the edge detectors that take no other setting.
+type EdgeParams struct {
+	Border BorderMode
+}
+
+// GradientOutput selects what the two-kernel gradient operators write.
+// Gx grows towards the right and Gy downwards, as image coordinates do,
+// whatever the signs of the operator's own kernels.
+type GradientOutput int
+
+const (
+	// GradientMagnitude is the gray length of the gradient.
+	GradientMagnitude GradientOutput = iota
+	// GradientAngle codes the direction of the gradient as hue and its
+	// length as brightness.
+	GradientAngle
+	// GradientX and GradientY show one derivative, 128 meaning zero.
+	GradientX
+	GradientY
+)
+
+type GradientParams struct {
+	Output GradientOutput
+	Border BorderMode
+}
+
+type KirschOutput int
+
+const (
+	KirschMagnitude KirschOutput = iota
+	// KirschDirection colours every pixel by the compass kernel with the
+	// strongest response, using the hues of GradientAngle.
+	KirschDirection
+)
+
+type KirschParams struct {
+	Output KirschOutput
+	Border BorderMode
+}
+
+var edgeEmpowerKernel = Kernel{Width: 3, Height: 3, Data: []float64{
+	0, 1, 0,
+	1, -4, 1,
+	0, 1, 0,
+}}
+
+var shiftEdgeKernels = [3]Kernel{
+	{Width: 3, Height: 3, Data: []float64{
+		0, 0, 0,
+		-1, 1, 0,
+		0, 0, 0,
+	}},
+	{Width: 3, Height: 3, Data: []float64{
+		0, -1, 0,
+		0, 1, 0,
+		0, 0, 0,
+	}},
+	{Width: 3, Height: 3, Data: []float64{
+		-1, 0, 0,
+		0, 1, 0,
+		0, 0, 0,
+	}},
+}
+
+var embossingKernels = [2]Kernel{
+	{Width: 3, Height: 3, Bias: 128, Data: []float64{
+		0, 1, 0,
+		-1, 0, 1,
+		0, -1, 0,
+	}},
+	{Width: 3, Height: 3, Bias: 128, Data: []float64{
+		0, -1, 0,
+		1, 0, -1,
+		0, 1, 0,
+	}},
+}
+
+var kirschKernels = []Kernel{
+	kernel3x3([3][3]float64{{5, 5, 5}, {-3, 0, -3}, {-3, -3, -3}}), // 0°
+	kernel3x3([3][3]float64{{-3, 5, 5}, {-3, 0, 5}, {-3, -3, -3}}), // 45°
+	kernel3x3([3][3]float64{{-3, -3, 5}, {-3, 0, 5}, {-3, -3, 5}}), // 90°
+	kernel3x3([3][3]float64{{-3, -3, -3}, {-3, 0, 5}, {-3, 5, 5}}), // 135°
+	kernel3x3([3][3]float64{{-3, -3, -3}, {-3, 0, -3}, {5, 5, 5}}), // 180°
+	kernel3x3([3][3]float64{{-3, -3, -3}, {5, 0, -3}, {5, 5, -3}}), // 225°
+	kernel3x3([3][3]float64{{5, -3, -3}, {5, 0, -3}, {5, -3, -3}}), // 270°
+	kernel3x3([3][3]float64{{5, 5, -3}, {5, 0, -3}, {-3, -3, -3}}), // 315°
+}
+
+// kirschBearings are the compass bearings of kirschKernels, clockwise from
+// north.
+var kirschBearings = []float64{0, 45, 90, 135, 180, 225, 270, 315}
+
+var pravitKernels = []Kernel{
+	kernel3x3([3][3]float64{{1, 0, -1}, {1, 0, -1}, {1, 0, -1}}),
+	kernel3x3([3][3]float64{{-1, -1, -1}, {0, 0, 0}, {1, 1, 1}}),
+}
+
+var sobelKernels = []Kernel{
+	kernel3x3([3][3]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}}),
+	kernel3x3([3][3]float64{{1, 2, 1}, {0, 0, 0}, {-1, -2, -1}}),
+}
+
+// robertsKernels are the 2x2 cross differences; their anchor is the
+// top-left tap.
+var robertsKernels = []Kernel{
+	NewKernel([][]float64{{1, 0}, {0, -1}}),
+	NewKernel([][]float64{{0, 1}, {-1, 0}}),
+}
+
+// gradientOperator is a pair of derivative kernels together with the way
+// their responses combine into the image-space gradient.
+type gradientOperator struct {
+	kernels []Kernel
+	// derivatives turns the responses into (gx, gy).
+	derivatives func(responses []float64) (gx, gy float64)
+	// gain scales a derivative into [-127.5, 127.5] for GradientX/Y.
+	gain float64
+}
+
+var (
+	sobelOperator = gradientOperator{
+		kernels: sobelKernels,
+		derivatives: func(responses []float64) (float64, float64) {
+			return responses[0], -responses[1]
+		},
+		gain: 1. / 8,
+	}
+	pravitOperator = gradientOperator{
+		kernels: pravitKernels,
+		derivatives: func(responses []float64) (float64, float64) {
+			return -responses[0], responses[1]
+		},
+		gain: 1. / 6,
+	}
+	// The Roberts kernels differentiate along the diagonals (-1, -1) and
+	// (1, -1).
+	robertsOperator = gradientOperator{
+		kernels: robertsKernels,
+		derivatives: func(responses []float64) (float64, float64) {
+			return (responses[1] - responses[0]) / 2, -(responses[0] + responses[1]) / 2
+		},
+		gain: 1. / 2,
+	}
+)
+
+func EdgeEmpower(ctx context.Context, src image.Image, params EdgeParams) (image.Image, error) {
+	return Convolve(ctx, src, edgeEmpowerKernel, ConvolveOptions{Border: params.Border, Gray: true, Abs: true})
+}
+
+func ShiftEdge(ctx context.Context, src image.Image, params ShiftEdgeParams) (image.Image, error) {
+	if params.Direction < ShiftVertical || params.Direction > ShiftDiagonal {
+		return nil, fmt.Errorf("filters: unknown shift direction %d", params.Direction)
+	}
+
+	return Convolve(ctx, src, shiftEdgeKernels[params.Direction], ConvolveOptions{
+		Border: params.Border,
+		Gray:   true,
+		Abs:    params.Direction == ShiftDiagonal,
+	})
+}
+
+func Embossing(ctx context.Context, src image.Image, params EmbossingParams) (image.Image, error) {
+	if params.Direction != EmbossIn && params.Direction != EmbossOut {
+		return nil, fmt.Errorf("filters: unknown emboss direction %d", params.Direction)
+	}
+
+	return Convolve(ctx, src, embossingKernels[params.Direction], ConvolveOptions{Border: params.Border, Gray: true})
+}
+
+// maxGradient keeps the strongest absolute response among the kernels.
+func maxGradient(responses []float64) float64 {
+	maxGradient := 0.0
+	for _, response := range responses {
+		maxGradient = math.Max(maxGradient, math.Abs(response))
+	}
+	return maxGradient
+}
+
+func hypotGradient(responses []float64) float64 {
+	return math.Hypot(responses[0], responses[1])
+}
+
+// angleColor codes a direction in degrees, counterclockwise from the right,
+// as hue and strength (0..255) as brightness.
+func angleColor(degrees, strength float64) (uint8, uint8, uint8) {
+	hue := math.Mod(degrees+360, 360)
+	return colorspace.HSVToRGB(hue, 1, checkForLimit(strength)/255)
+}
+
+func biasedGray(value float64) (uint8, uint8, uint8) {
+	gray := uint8(checkForLimit(128 + value))
+	return gray, gray, gray
+}
+
+func Kirsch(ctx context.Context, src image.Image, params KirschParams) (image.Image, error) {
+	switch params.Output {
+	case KirschMagnitude:
+		return gradientImage(ctx, src, kirschKernels, params.Border, maxGradient)
+	case KirschDirection:
+		return gradientColorImage(ctx, src, kirschKernels, params.Border, func(responses []float64) (uint8, uint8, uint8) {
+			winner := 0
+			for i, response := range responses {
+				if response > responses[winner] {
+					winner = i
+				}
+			}
+			return angleColor(90-kirschBearings[winner], maxGradient(responses))
+		})
+	}
+	return nil, fmt.Errorf("filters: unknown Kirsch output %d", params.Output)
+}
+
+// gradient renders the output of a two-kernel operator; magnitude keeps the
+// operator's own way of combining the responses.
+func gradient(ctx context.Context, src image.Image, op gradientOperator, params GradientParams, magnitude func(responses []float64) float64) (image.Image, error) {
+	switch params.Output {
+	case GradientMagnitude:
+		return gradientImage(ctx, src, op.kernels, params.Border, magnitude)
+	case GradientAngle:
+		return gradientColorImage(ctx, src, op.kernels, params.Border, func(responses []float64) (uint8, uint8, uint8) {
+			gx, gy := op.derivatives(responses)
+			return angleColor(math.Atan2(-gy, gx)*180/math.Pi, magnitude(responses))
+		})
+	case GradientX:
+		return gradientColorImage(ctx, src, op.kernels, params.Border, func(responses []float64) (uint8, uint8, uint8) {
+			gx, _ := op.derivatives(responses)
+			return biasedGray(gx * op.gain)
+		})
+	case GradientY:
+		return gradientColorImage(ctx, src, op.kernels, params.Border, func(responses []float64) (uint8, uint8, uint8) {
+			_, gy := op.derivatives(responses)
+			return biasedGray(gy * op.gain)
+		})
+	}
+	return nil, fmt.Errorf("filters: unknown gradient output %d", params.Output)
+}
+
+func Pravit(ctx context.Context, src image.Image, params GradientParams) (image.Image, error) {
+	return gradient(ctx, src, pravitOperator, params, maxGradient)
+}
+
+func Sobel(ctx context.Context, src image.Image, params GradientParams) (image.Image, error) {
+	return gradient(ctx, src, sobelOperator, params, hypotGradient)
+}
+
+func Roberts(ctx context.Context, src image.Image, params GradientParams) (image.Image, error) {
+	return gradient(ctx, src, robertsOperator, params, hypotGradient)
+}
+
+var (
+	shiftDirectionNames  = []string{"Vertical", "Horizontal", "Diagonal"}
+	embossDirectionNames = []string{"In", "Out"}
+	gradientOutputNames  = []string{"Magnitude", "Direction as hue", "Gx", "Gy"}
+	kirschOutputNames    = []string{"Magnitude", "Direction as hue"}
+)
+
+var gradientOutputParam = Param{
+	Name: "output", Label: "Output", Kind: ParamChoice, Choices: gradientOutputNames, Default: "Magnitude",
+}
+
+func gradientParams(p Params) GradientParams {
+	return GradientParams{Output: GradientOutput(p.Index("output", gradientOutputNames)), Border: borderOf(p)}
+}
+
+func init() {
+	Register(&basicFilter{
+		name: "edge-empower", title: "Edge empower", category: EdgeDetection,
+		params: []Param{borderParam},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return EdgeEmpower(ctx, src, EdgeParams{Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "shift-edge", title: "Shift edge", category: EdgeDetection,
+		params: []Param{
+			{Name: "direction", Label: "Direction", Kind: ParamChoice, Choices: shiftDirectionNames, Default: "Vertical"},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return ShiftEdge(ctx, src, ShiftEdgeParams{Direction: ShiftDirection(p.Index("direction", shiftDirectionNames)), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "embossing", title: "Embossing", category: EdgeDetection,
+		params: []Param{
+			{Name: "direction", Label: "Direction", Kind: ParamChoice, Choices: embossDirectionNames, Default: "In"},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Embossing(ctx, src, EmbossingParams{Direction: EmbossDirection(p.Index("direction", embossDirectionNames)), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "kirsch", title: "Kirsch", category: EdgeDetection,
+		params: []Param{
+			{Name: "output", Label: "Output", Kind: ParamChoice, Choices: kirschOutputNames, Default: "Magnitude"},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Kirsch(ctx, src, KirschParams{Output: KirschOutput(p.Index("output", kirschOutputNames)), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "pravit", title: "Pravit", category: EdgeDetection,
+		params: []Param{gradientOutputParam, borderParam},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Pravit(ctx, src, gradientParams(p))
+		},
+	})
+	Register(&basicFilter{
+		name: "sobel", title: "Sobel", category: EdgeDetection,
+		params: []Param{gradientOutputParam, borderParam},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Sobel(ctx, src, gradientParams(p))
+		},
+	})
+	Register(&basicFilter{
+		name: "roberts", title: "Roberts", category: EdgeDetection,
+		params: []Param{gradientOutputParam, borderParam},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Roberts(ctx, src, gradientParams(p))
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestGradientOrientation(t *testing.T) {
+	// Brightness grows to the right, so every operator has to agree on a
+	// positive Gx, a zero Gy and the hue of 0°, red.
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			v := uint8(x * 30)
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	ctx := context.Background()
+	center := func(img image.Image) color.RGBA {
+		return engine.ToRGBA(img).RGBAAt(4, 4)
+	}
+
+	for name, apply := range map[string]func(GradientParams) (image.Image, error){
+		"sobel":   func(p GradientParams) (image.Image, error) { return Sobel(ctx, img, p) },
+		"pravit":  func(p GradientParams) (image.Image, error) { return Pravit(ctx, img, p) },
+		"roberts": func(p GradientParams) (image.Image, error) { return Roberts(ctx, img, p) },
+	} {
+		gx, err := apply(GradientParams{Output: GradientX})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := center(gx); c.R <= 128 {
+			t.Errorf("%s: Gx %d, want above 128", name, c.R)
+		}
+
+		gy, _ := apply(GradientParams{Output: GradientY})
+		if c := center(gy); c.R != 128 {
+			t.Errorf("%s: Gy %d, want 128", name, c.R)
+		}
+
+		angle, _ := apply(GradientParams{Output: GradientAngle})
+		if c := center(angle); c.G != 0 || c.B != 0 || c.R == 0 {
+			t.Errorf("%s: angle colour %v, want red", name, c)
+		}
+	}
+
+	direction, err := Kirsch(ctx, img, KirschParams{Output: KirschDirection})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := center(direction); c.G != 0 || c.B != 0 || c.R == 0 {
+		t.Errorf("kirsch: direction colour %v, want red", c)
+	}
+}
@@ -0,0 +1,249 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/engine"
+)
+
+type EqualizeMode int
+
+const (
+	// EqualizeLuma equalizes the brightness and keeps the colours.
+	EqualizeLuma EqualizeMode = iota
+	// EqualizeChannels equalizes R, G and B separately, which also
+	// stretches the colour balance.
+	EqualizeChannels
+)
+
+type EqualizeParams struct {
+	Mode EqualizeMode
+}
+
+type CLAHEParams struct {
+	// TilesX and TilesY split the image into the grid of contextual regions.
+	TilesX, TilesY int
+	// ClipLimit caps every histogram bin at this multiple of the average
+	// bin count; 1 leaves the image almost unchanged, larger values allow
+	// more contrast.
+	ClipLimit float64
+}
+
+const maxCLAHETiles = 64
+
+func (p CLAHEParams) validate() error {
+	if p.TilesX < 1 || p.TilesX > maxCLAHETiles || p.TilesY < 1 || p.TilesY > maxCLAHETiles {
+		return fmt.Errorf("filters: CLAHE tile grid %dx%d out of range [1, %d]", p.TilesX, p.TilesY, maxCLAHETiles)
+	}
+	if p.ClipLimit < 1 {
+		return fmt.Errorf("filters: CLAHE clip limit %g must be at least 1", p.ClipLimit)
+	}
+	return nil
+}
+
+// equalizeTable maps the levels of histogram so that their cumulative
+// distribution becomes linear over 0..255.
+func equalizeTable(histogram [256]int) [256]uint8 {
+	cdf := Cumulative(histogram)
+	total := cdf[255]
+
+	cdfMin := 0
+	for _, count := range cdf {
+		if count > 0 {
+			cdfMin = count
+			break
+		}
+	}
+
+	var table [256]uint8
+	for level := range table {
+		if total == cdfMin {
+			// A single level: nothing to spread.
+			table[level] = uint8(level)
+			continue
+		}
+		table[level] = uint8(math.Round(checkForLimit(float64(cdf[level]-cdfMin) / float64(total-cdfMin) * 255)))
+	}
+	return table
+}
+
+func clampByte(value int) uint8 {
+	return uint8(min(max(value, 0), 255))
+}
+
+// shiftLuma moves the brightness of a pixel to level by adding the same
+// amount to every channel, which is replacing Y in YCbCr.
+func shiftLuma(r, g, b, level uint8) (uint8, uint8, uint8) {
+	delta := int(level) - int(LumaLevel(r, g, b))
+	return clampByte(int(r) + delta), clampByte(int(g) + delta), clampByte(int(b) + delta)
+}
+
+func Equalize(ctx context.Context, src image.Image, params EqualizeParams) (image.Image, error) {
+	histograms := Histograms(src)
+
+	switch params.Mode {
+	case EqualizeLuma:
+		table := equalizeTable(histograms[ChannelLuma])
+		return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+			r, g, b = shiftLuma(r, g, b, table[LumaLevel(r, g, b)])
+			return r, g, b, a
+		})
+	case EqualizeChannels:
+		red := equalizeTable(histograms[ChannelRed])
+		green := equalizeTable(histograms[ChannelGreen])
+		blue := equalizeTable(histograms[ChannelBlue])
+		return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+			return red[r], green[g], blue[b], a
+		})
+	}
+	return nil, fmt.Errorf("filters: unknown equalize mode %d", params.Mode)
+}
+
+// clipHistogram caps every bin at limit and spreads the clipped counts
+// evenly over all bins.
+func clipHistogram(histogram *[256]int, limit int) {
+	excess := 0
+	for level, count := range histogram {
+		if count > limit {
+			excess += count - limit
+			histogram[level] = limit
+		}
+	}
+
+	perBin, rest := excess/256, excess%256
+	for level := range histogram {
+		histogram[level] += perBin
+		if level < rest {
+			histogram[level]++
+		}
+	}
+}
+
+// CLAHE runs contrast limited adaptive histogram equalization on the
+// brightness: every tile gets its own clipped equalization table and each
+// pixel blends the tables of the four nearest tile centres.
+func CLAHE(ctx context.Context, src image.Image, params CLAHEParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	in := engine.ToRGBA(src)
+	bounds := in.Bounds()
+	if bounds.Empty() {
+		return image.NewRGBA(bounds), nil
+	}
+
+	tilesX := min(params.TilesX, bounds.Dx())
+	tilesY := min(params.TilesY, bounds.Dy())
+	tileW := float64(bounds.Dx()) / float64(tilesX)
+	tileH := float64(bounds.Dy()) / float64(tilesY)
+
+	tileRect := func(tx, ty int) image.Rectangle {
+		return image.Rect(
+			bounds.Min.X+int(float64(tx)*tileW), bounds.Min.Y+int(float64(ty)*tileH),
+			bounds.Min.X+int(float64(tx+1)*tileW), bounds.Min.Y+int(float64(ty+1)*tileH),
+		)
+	}
+
+	tables := make([][256]uint8, tilesX*tilesY)
+
+	// Rows of tiles are spread over the workers like rows of pixels.
+	err := engine.Rows(engine.SubProgress(ctx, 0, 0.3), image.Rect(0, 0, 1, tilesY), func(ty0, ty1 int) {
+		for ty := ty0; ty < ty1; ty++ {
+			for tx := range tilesX {
+				rect := tileRect(tx, ty)
+
+				var histogram [256]int
+				for y := rect.Min.Y; y < rect.Max.Y; y++ {
+					i := in.PixOffset(rect.Min.X, y)
+					for x := rect.Min.X; x < rect.Max.X; x, i = x+1, i+4 {
+						histogram[LumaLevel(in.Pix[i], in.Pix[i+1], in.Pix[i+2])]++
+					}
+				}
+
+				pixels := rect.Dx() * rect.Dy()
+				clipHistogram(&histogram, max(int(params.ClipLimit*float64(pixels)/256), 1))
+
+				cdf := Cumulative(histogram)
+				table := &tables[ty*tilesX+tx]
+				for level := range table {
+					table[level] = uint8(math.Round(float64(cdf[level]) * 255 / float64(pixels)))
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// neighbours returns the two tiles whose centres surround pos along one
+	// axis and the weight of the second one.
+	neighbours := func(pos, tileSize float64, tiles int) (int, int, float64) {
+		f := pos/tileSize - 0.5
+		first := int(math.Floor(f))
+		weight := f - float64(first)
+		if first < 0 {
+			return 0, 0, 0
+		}
+		if first >= tiles-1 {
+			return tiles - 1, tiles - 1, 0
+		}
+		return first, first + 1, weight
+	}
+
+	dst := image.NewRGBA(bounds)
+
+	err = engine.Rows(engine.SubProgress(ctx, 0.3, 1), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			ty0, ty1, wy := neighbours(float64(y-bounds.Min.Y)+0.5, tileH, tilesY)
+
+			i := in.PixOffset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, i = x+1, i+4 {
+				tx0, tx1, wx := neighbours(float64(x-bounds.Min.X)+0.5, tileW, tilesX)
+
+				r, g, b := in.Pix[i], in.Pix[i+1], in.Pix[i+2]
+				level := LumaLevel(r, g, b)
+
+				top := (1-wx)*float64(tables[ty0*tilesX+tx0][level]) + wx*float64(tables[ty0*tilesX+tx1][level])
+				bottom := (1-wx)*float64(tables[ty1*tilesX+tx0][level]) + wx*float64(tables[ty1*tilesX+tx1][level])
+				equalized := uint8(math.Round((1-wy)*top + wy*bottom))
+
+				dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = shiftLuma(r, g, b, equalized)
+				dst.Pix[i+3] = in.Pix[i+3]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+var equalizeModeNames = []string{"Luminance", "Per channel"}
+
+func init() {
+	Register(&basicFilter{
+		name: "equalize", title: "Equalize histogram", category: Adjustments,
+		params: []Param{
+			{Name: "mode", Label: "Mode", Kind: ParamChoice, Choices: equalizeModeNames, Default: "Luminance"},
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Equalize(ctx, src, EqualizeParams{Mode: EqualizeMode(p.Index("mode", equalizeModeNames))})
+		},
+	})
+	Register(&basicFilter{
+		name: "clahe", title: "CLAHE", category: Adjustments,
+		params: []Param{
+			{Name: "tiles-x", Label: "Tiles across", Kind: ParamInt, Min: 1, Max: maxCLAHETiles, Step: 1, Default: 8, Slider: true},
+			{Name: "tiles-y", Label: "Tiles down", Kind: ParamInt, Min: 1, Max: maxCLAHETiles, Step: 1, Default: 8, Slider: true},
+			{Name: "clip", Label: "Clip limit", Kind: ParamFloat, Min: 1, Max: 16, Step: 0.1, Default: 2., Slider: true},
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return CLAHE(ctx, src, CLAHEParams{TilesX: p.Int("tiles-x"), TilesY: p.Int("tiles-y"), ClipLimit: p.Float("clip")})
+		},
+	})
+}
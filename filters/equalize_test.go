@@ -0,0 +1,77 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEqualizeTable(t *testing.T) {
+	var histogram [256]int
+	histogram[100] = 5
+	histogram[110] = 5
+
+	table := equalizeTable(histogram)
+	if table[100] != 0 || table[110] != 255 {
+		t.Errorf("table maps 100 to %d and 110 to %d, want 0 and 255", table[100], table[110])
+	}
+
+	var single [256]int
+	single[42] = 3
+	if table := equalizeTable(single); table[42] != 42 {
+		t.Errorf("a single level moved to %d", table[42])
+	}
+}
+
+func TestClipHistogram(t *testing.T) {
+	var histogram [256]int
+	histogram[0] = 1000
+	histogram[1] = 10
+
+	clipHistogram(&histogram, 20)
+
+	total := 0
+	for _, count := range histogram {
+		total += count
+	}
+	if total != 1010 {
+		t.Errorf("clipping changed the pixel count to %d", total)
+	}
+	if histogram[0] > 20+4 {
+		t.Errorf("bin 0 still holds %d", histogram[0])
+	}
+}
+
+func TestEqualizeRamp(t *testing.T) {
+	// A ramp already has a flat histogram and must stay as it is.
+	img := image.NewRGBA(image.Rect(0, 0, 256, 4))
+	for y := range 4 {
+		for x := range 256 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(x), uint8(x), 255})
+		}
+	}
+
+	for _, mode := range []EqualizeMode{EqualizeLuma, EqualizeChannels} {
+		got, err := Equalize(context.Background(), img, EqualizeParams{Mode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := compareImages(got, img); diff != "" {
+			t.Errorf("mode %d: %s", mode, diff)
+		}
+	}
+}
+
+func TestCLAHEParams(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for _, params := range []CLAHEParams{{0, 8, 2}, {8, 100, 2}, {8, 8, 0.5}} {
+		if _, err := CLAHE(context.Background(), img, params); err == nil {
+			t.Errorf("%+v: no error", params)
+		}
+	}
+	// More tiles than pixels is fine.
+	if _, err := CLAHE(context.Background(), img, CLAHEParams{TilesX: 8, TilesY: 8, ClipLimit: 2}); err != nil {
+		t.Error(err)
+	}
+}
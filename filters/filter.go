@@ -0,0 +1,278 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"photoshop/lut"
+)
+
+type Category string
+
+const (
+	PointOps      Category = "Point operations"
+	Adjustments   Category = "Adjustments"
+	Smoothing     Category = "Smoothing"
+	EdgeDetection Category = "Edge detection"
+	MorphologyOps Category = "Morphology"
+)
+
+// Categories lists the categories in the order the UI shows them.
+func Categories() []Category {
+	return []Category{PointOps, Adjustments, Smoothing, EdgeDetection, MorphologyOps}
+}
+
+type ParamKind int
+
+const (
+	ParamInt ParamKind = iota
+	ParamFloat
+	ParamChoice
+	// ParamText is free text the filter parses itself.
+	ParamText
+)
+
+// Param describes one input of a filter. Default is an int, float64 or
+// string matching Kind; a nil Default makes the parameter required.
+type Param struct {
+	Name    string
+	Label   string
+	Kind    ParamKind
+	Min     float64
+	Max     float64
+	Step    float64
+	Default any
+	Choices []string
+	// Slider asks the UI for a slider instead of a text entry.
+	Slider bool
+}
+
+// Params maps parameter names to values: int for ParamInt, float64 for
+// ParamFloat, the choice string for ParamChoice and the text for
+// ParamText.
+type Params map[string]any
+
+func (p Params) Int(name string) int {
+	switch v := p[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func (p Params) Float(name string) float64 {
+	switch v := p[name].(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func (p Params) String(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Index returns the position of the chosen value among choices, or -1.
+func (p Params) Index(name string, choices []string) int {
+	value := p.String(name)
+	for i, choice := range choices {
+		if choice == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseNumber accepts a plain number or a fraction like "1/2", as typed
+// into numeric parameters.
+func ParseNumber(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+
+	if numerator, denominator, ok := strings.Cut(text, "/"); ok {
+		firstValue, err := strconv.ParseFloat(numerator, 64)
+		if err != nil {
+			return 0, false
+		}
+		secondValue, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || secondValue == 0 {
+			return 0, false
+		}
+		return firstValue / secondValue, true
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+type Filter interface {
+	// Name is the unique identifier used by the registry and the CLI.
+	Name() string
+	// Title is the human-readable label shown on buttons.
+	Title() string
+	Category() Category
+	Params() []Param
+	// Apply runs the filter, reporting progress through ctx (see
+	// engine.WithProgress) and stopping early with ctx.Err() once ctx is
+	// cancelled.
+	Apply(ctx context.Context, src image.Image, params Params) (image.Image, error)
+}
+
+type basicFilter struct {
+	name     string
+	title    string
+	category Category
+	params   []Param
+	apply    func(ctx context.Context, src image.Image, params Params) (image.Image, error)
+}
+
+func (f *basicFilter) Name() string       { return f.name }
+func (f *basicFilter) Title() string      { return f.title }
+func (f *basicFilter) Category() Category { return f.category }
+func (f *basicFilter) Params() []Param    { return f.params }
+
+func (f *basicFilter) Apply(ctx context.Context, src image.Image, params Params) (image.Image, error) {
+	resolved, err := resolveParams(f.params, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return f.apply(ctx, src, resolved)
+}
+
+// Reporter is implemented by filters that derive a value from the image
+// worth showing next to their parameters, such as an automatic threshold.
+type Reporter interface {
+	Report(ctx context.Context, src image.Image, params Params) (string, error)
+}
+
+// reportingFilter is a basicFilter that also implements Reporter.
+type reportingFilter struct {
+	basicFilter
+	report func(ctx context.Context, src image.Image, params Params) (string, error)
+}
+
+func (f *reportingFilter) Report(ctx context.Context, src image.Image, params Params) (string, error) {
+	resolved, err := resolveParams(f.params, params)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", f.name, err)
+	}
+	return f.report(ctx, src, resolved)
+}
+
+// LUTFilter is implemented by filters that only remap colours, so that
+// several of them can be chained and exported as one lookup table.
+type LUTFilter interface {
+	LUT(params Params) (lut.LUT, error)
+}
+
+// lutFilter is a basicFilter that also implements LUTFilter.
+type lutFilter struct {
+	basicFilter
+	table func(params Params) (lut.LUT, error)
+}
+
+func (f *lutFilter) LUT(params Params) (lut.LUT, error) {
+	resolved, err := resolveParams(f.params, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return f.table(resolved)
+}
+
+// resolveParams fills in defaults and checks that required values are set
+// and lie within the range or choices of their parameter.
+func resolveParams(schema []Param, params Params) (Params, error) {
+	resolved := make(Params, len(schema))
+	for _, param := range schema {
+		value, ok := params[param.Name]
+		if !ok {
+			if param.Default == nil {
+				return nil, fmt.Errorf("missing parameter %q", param.Name)
+			}
+			value = param.Default
+		}
+		if err := checkParam(param, value); err != nil {
+			return nil, err
+		}
+		resolved[param.Name] = value
+	}
+	return resolved, nil
+}
+
+func checkParam(param Param, value any) error {
+	switch param.Kind {
+	case ParamInt, ParamFloat:
+		var number float64
+		switch v := value.(type) {
+		case int:
+			number = float64(v)
+		case float64:
+			number = v
+		default:
+			return fmt.Errorf("parameter %q must be a number, got %v", param.Name, value)
+		}
+		if math.IsNaN(number) || number < param.Min || number > param.Max {
+			return fmt.Errorf("parameter %q must be between %v and %v, got %v", param.Name, param.Min, param.Max, value)
+		}
+	case ParamChoice:
+		if choice, _ := value.(string); !slices.Contains(param.Choices, choice) {
+			return fmt.Errorf("parameter %q must be one of %s, got %v", param.Name, strings.Join(param.Choices, ", "), value)
+		}
+	}
+	return nil
+}
+
+var registry = map[string]Filter{}
+var registryOrder []Filter
+
+// Register adds f to the registry; registering a name twice panics.
+func Register(f Filter) {
+	if _, ok := registry[f.Name()]; ok {
+		panic("filters: duplicate filter " + f.Name())
+	}
+	registry[f.Name()] = f
+	registryOrder = append(registryOrder, f)
+}
+
+func Lookup(name string) (Filter, bool) {
+	f, ok := registry[name]
+	return f, ok
+}
+
+// All returns the registered filters in registration order.
+func All() []Filter {
+	return append([]Filter(nil), registryOrder...)
+}
+
+func ByCategory(category Category) []Filter {
+	var res []Filter
+	for _, f := range registryOrder {
+		if f.Category() == category {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Names returns the sorted names of all registered filters.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,252 @@
+package filters
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photoshop/engine"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata/golden")
+
+// goldenTolerance is the largest per-channel difference still accepted, to
+// absorb floating point differences between platforms (e.g. fused
+// multiply-add on arm64).
+const goldenTolerance = 1
+
+// testParams holds the required parameters of the filters that have some,
+// and values for the filters whose defaults leave the image unchanged or
+// flat; the other filters run with their defaults.
+var testParams = map[string]Params{
+	"negative":          {"ceiling": 200},
+	"brightness":        {"value": 40},
+	"gamma":             {"gamma": 0.5},
+	"binarization":      {"threshold": 128},
+	"quantization":      {"quants": 4},
+	"increase-contrast": {"q1": 30, "q2": 200},
+	"decrease-contrast": {"q1": 30, "q2": 200},
+	"median":            {"size": 5},
+	"gauss":             {"size": 5},
+	"hue-saturation":    {"hue": 60., "saturation": 30., "lightness": -10., "greens": -80.},
+	"lab-adjust":        {"lightness": 10., "b": -20., "chroma": 40.},
+	"curves":            {"rgb": "0,0 64,40 190,220 255,255", "red": "0,20 255,255"},
+	"levels":            {"in-black": 20, "in-white": 230, "gamma": 1.4, "out-black": 10},
+	"apply-lut":         {"file": "testdata/fixtures/teal-orange.cube"},
+}
+
+type goldenCase struct {
+	name   string
+	filter string
+	params Params
+}
+
+func goldenCases() []goldenCase {
+	var cases []goldenCase
+	for _, f := range All() {
+		cases = append(cases, goldenCase{name: f.Name(), filter: f.Name(), params: testParams[f.Name()]})
+	}
+
+	for _, border := range borderModeNames[1:] {
+		cases = append(cases, goldenCase{
+			name:   "gauss-" + strings.ToLower(border),
+			filter: "gauss",
+			params: Params{"size": 5, "border": border},
+		})
+	}
+
+	for _, output := range gradientOutputNames[1:] {
+		cases = append(cases, goldenCase{
+			name:   "sobel-" + strings.ToLower(strings.ReplaceAll(output, " ", "-")),
+			filter: "sobel",
+			params: Params{"output": output},
+		})
+	}
+	cases = append(cases, goldenCase{name: "kirsch-direction-as-hue", filter: "kirsch", params: Params{"output": "Direction as hue"}})
+
+	cases = append(cases, goldenCase{
+		name:   "dilate-grayscale-ellipse",
+		filter: "dilate",
+		params: Params{"mode": "Grayscale", "shape": "Ellipse", "width": 5, "height": 3},
+	})
+
+	cases = append(cases, goldenCase{
+		name:   "hue-saturation-colorize",
+		filter: "hue-saturation",
+		params: Params{"hue": 30., "saturation": -20., "colorize": "On"},
+	})
+
+	cases = append(cases, goldenCase{
+		name:   "apply-lut-trilinear",
+		filter: "apply-lut",
+		params: Params{"file": "testdata/fixtures/teal-orange.cube", "interpolation": "Trilinear"},
+	})
+
+	for _, method := range ditherMethodNames {
+		cases = append(cases, goldenCase{
+			name:   "quantization-" + strings.ToLower(strings.ReplaceAll(method, " ", "-")),
+			filter: "quantization",
+			params: Params{"quants": 3, "dither": method},
+		})
+	}
+	for _, method := range []string{"None", "Floyd-Steinberg", "Bayer 8x8"} {
+		cases = append(cases, goldenCase{
+			name:   "quantization-1bit-" + strings.ToLower(strings.ReplaceAll(method, " ", "-")),
+			filter: "quantization",
+			params: Params{"dither": method, "output": "1-bit"},
+		})
+	}
+
+	for _, method := range paletteMethodNames[1:] {
+		cases = append(cases, goldenCase{
+			name:   "palette-" + strings.ToLower(method),
+			filter: "palette",
+			params: Params{"method": method},
+		})
+	}
+	for _, method := range []string{"Floyd-Steinberg", "Bayer 4x4"} {
+		cases = append(cases, goldenCase{
+			name:   "palette-8-" + strings.ToLower(strings.ReplaceAll(method, " ", "-")),
+			filter: "palette",
+			params: Params{"colors": 8, "dither": method},
+		})
+	}
+
+	for _, method := range thresholdMethodNames[1:] {
+		cases = append(cases, goldenCase{
+			name:   "binarization-" + strings.ToLower(strings.ReplaceAll(method, " ", "-")),
+			filter: "binarization",
+			params: Params{"method": method},
+		})
+	}
+
+	return cases
+}
+
+func loadPNG(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return img
+}
+
+func savePNG(t *testing.T, path string, img image.Image) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encodeRoundTrip returns img as it reads back from a PNG file: the origin
+// moves to (0, 0) and colours above alpha are clipped as on saving.
+func encodeRoundTrip(t *testing.T, img image.Image) image.Image {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// compareImages returns a description of the first difference larger than
+// goldenTolerance, or "" when the images match. Only the sizes of the
+// bounds have to agree.
+func compareImages(got, want image.Image) string {
+	if got.Bounds().Size() != want.Bounds().Size() {
+		return fmt.Sprintf("size %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+
+	gotRGBA, wantRGBA := engine.ToRGBA(got), engine.ToRGBA(want)
+	gotMin, wantMin := got.Bounds().Min, want.Bounds().Min
+	size := got.Bounds().Size()
+
+	for y := range size.Y {
+		for x := range size.X {
+			i := gotRGBA.PixOffset(gotMin.X+x, gotMin.Y+y)
+			j := wantRGBA.PixOffset(wantMin.X+x, wantMin.Y+y)
+			for c := range 4 {
+				diff := int(gotRGBA.Pix[i+c]) - int(wantRGBA.Pix[j+c])
+				if diff > goldenTolerance || diff < -goldenTolerance {
+					return fmt.Sprintf("pixel (%d, %d) is %v, want %v", x, y, gotRGBA.Pix[i:i+4], wantRGBA.Pix[j:j+4])
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// TestGolden runs every filter on the fixtures and compares the results
+// with testdata/golden. Run "go test ./filters -update" to accept new
+// output after an intended change.
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "fixtures", "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata/fixtures")
+	}
+
+	for _, fixture := range fixtures {
+		src := loadPNG(t, fixture)
+		fixtureName := strings.TrimSuffix(filepath.Base(fixture), ".png")
+
+		for _, tc := range goldenCases() {
+			t.Run(fixtureName+"/"+tc.name, func(t *testing.T) {
+				f, ok := Lookup(tc.filter)
+				if !ok {
+					t.Fatalf("filter %q is not registered", tc.filter)
+				}
+
+				got, err := f.Apply(context.Background(), src, tc.params)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				goldenPath := filepath.Join("testdata", "golden", fixtureName, tc.name+".png")
+				if *update {
+					savePNG(t, goldenPath, got)
+					return
+				}
+
+				if diff := compareImages(encodeRoundTrip(t, got), loadPNG(t, goldenPath)); diff != "" {
+					t.Errorf("%s differs from %s: %s", tc.name, goldenPath, diff)
+				}
+			})
+		}
+	}
+}
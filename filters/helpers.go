@@ -0,0 +1,106 @@
+package filters
+
+import (
+	"image"
+
+	"photoshop/engine"
+)
+
+func checkForLimit(value float64) float64 {
+	if value > 255. {
+		return 255.
+	} else if value < 0. {
+		return 0.
+	}
+	return value
+}
+
+// Luminance returns the grayscale value of an 8-bit RGB triple using the
+// weights every filter in this package relies on.
+func Luminance(r, g, b uint32) float64 {
+	return 0.3*float64(r) + 0.59*float64(g) + 0.11*float64(b)
+}
+
+func pascalRow(n int) []float64 {
+	res := make([]float64, n)
+	elem := 1.
+	res[0] = 1.
+	res[n-1] = 1.
+	end := float64(n)/2. + 1
+
+	for k := 1.; k < end; k++ {
+		elem *= (float64(n-1) + 1 - k) / k
+
+		pos := int(k)
+
+		res[pos] = elem
+		res[n-pos-1] = elem
+	}
+
+	return res
+}
+
+func GaussKernelByPascalRow(row []float64) [][]float64 {
+	res := make([][]float64, 0, len(row))
+
+	sum := 0.
+
+	end := len(row)/2 + 1
+
+	for range row {
+		res = append(res, make([]float64, len(row)))
+	}
+
+	n := len(row) - 1
+
+	setVal := func(i, j int, val float64) {
+		res[i][j] = val
+		res[n-i][j] = val
+		res[i][n-j] = val
+		res[n-i][n-j] = val
+	}
+
+	for i := range end {
+		for j := range end {
+			val := float64(row[i] * row[j])
+
+			setVal(i, j, val)
+
+			sum += val
+
+			var t1, t2 bool
+
+			if i != n-i {
+				t1 = true
+				sum += val
+			}
+
+			if j != n-j {
+				t2 = true
+				sum += val
+			}
+
+			if t1 && t2 {
+				sum += val
+			}
+		}
+	}
+
+	for i := range end {
+		for j := range end {
+			val := res[i][j] / sum
+
+			setVal(i, j, val)
+		}
+	}
+
+	return res
+}
+
+// lumaPlane precomputes the luminance of every pixel for the filters that
+// work on brightness only.
+func lumaPlane(src image.Image) *engine.Plane {
+	return engine.PlaneOf(src, func(r, g, b, _ uint8) float64 {
+		return Luminance(uint32(r), uint32(g), uint32(b))
+	})
+}
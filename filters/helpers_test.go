@@ -0,0 +1,62 @@
+package filters
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestPascalRow(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []float64
+	}{
+		{2, []float64{1, 1}},
+		{3, []float64{1, 2, 1}},
+		{4, []float64{1, 3, 3, 1}},
+		{5, []float64{1, 4, 6, 4, 1}},
+		{7, []float64{1, 6, 15, 20, 15, 6, 1}},
+		{9, []float64{1, 8, 28, 56, 70, 56, 28, 8, 1}},
+	}
+
+	for _, tc := range tests {
+		if got := pascalRow(tc.n); !slices.Equal(got, tc.want) {
+			t.Errorf("pascalRow(%d) = %v, want %v", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestGaussKernelByPascalRow(t *testing.T) {
+	for _, size := range []int{3, 5, 7, 9, 15} {
+		row := pascalRow(size)
+		kernel := GaussKernelByPascalRow(row)
+
+		var rowSum float64
+		for _, value := range row {
+			rowSum += value
+		}
+
+		var sum float64
+		for i := range size {
+			for j := range size {
+				want := row[i] * row[j] / (rowSum * rowSum)
+				if math.Abs(kernel[i][j]-want) > 1e-12 {
+					t.Fatalf("size %d: kernel[%d][%d] = %v, want %v", size, i, j, kernel[i][j], want)
+				}
+				sum += kernel[i][j]
+			}
+		}
+
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("size %d: weights sum to %v, want 1", size, sum)
+		}
+	}
+}
+
+func TestCheckForLimit(t *testing.T) {
+	for value, want := range map[float64]float64{-3: 0, 0: 0, 17.5: 17.5, 255: 255, 300: 255} {
+		if got := checkForLimit(value); got != want {
+			t.Errorf("checkForLimit(%v) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"math"
+	"sync"
+
+	"photoshop/engine"
+)
+
+type Channel int
+
+const (
+	ChannelRed Channel = iota
+	ChannelGreen
+	ChannelBlue
+	ChannelLuma
+	ChannelAlpha
+)
+
+var channelNames = [...]string{"Red", "Green", "Blue", "Luminance", "Alpha"}
+
+func (c Channel) String() string {
+	return channelNames[c]
+}
+
+// Channels lists all channels in display order.
+func Channels() []Channel {
+	return []Channel{ChannelRed, ChannelGreen, ChannelBlue, ChannelLuma, ChannelAlpha}
+}
+
+// ChannelHistograms holds the pixel count of every level (0-255) per
+// channel, indexed by Channel.
+type ChannelHistograms [5][256]int
+
+// LumaLevel returns the brightness level of an 8-bit RGB triple. The
+// epsilon keeps grays on their own level: the weights add up to slightly
+// less than 1 in floating point, so 0.3*v + 0.59*v + 0.11*v truncates to
+// v-1 for many v.
+func LumaLevel(r, g, b uint8) uint8 {
+	return uint8(Luminance(uint32(r), uint32(g), uint32(b)) + 1e-9)
+}
+
+// Histograms counts the levels of every channel of src.
+func Histograms(src image.Image) ChannelHistograms {
+	in := engine.ToRGBA(src)
+	bounds := in.Bounds()
+
+	var mu sync.Mutex
+	var histograms ChannelHistograms
+
+	engine.Rows(context.Background(), bounds, func(y0, y1 int) {
+		var local ChannelHistograms
+		for y := y0; y < y1; y++ {
+			i := in.PixOffset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, i = x+1, i+4 {
+				r, g, b, a := in.Pix[i], in.Pix[i+1], in.Pix[i+2], in.Pix[i+3]
+				local[ChannelRed][r]++
+				local[ChannelGreen][g]++
+				local[ChannelBlue][b]++
+				local[ChannelLuma][LumaLevel(r, g, b)]++
+				local[ChannelAlpha][a]++
+			}
+		}
+
+		mu.Lock()
+		for channel := range local {
+			for level, count := range local[channel] {
+				histograms[channel][level] += count
+			}
+		}
+		mu.Unlock()
+	})
+
+	return histograms
+}
+
+// Histogram counts the pixels of every brightness level (0-255).
+func Histogram(src image.Image) [256]int {
+	return Histograms(src)[ChannelLuma]
+}
+
+// Cumulative returns the running totals of histogram.
+func Cumulative(histogram [256]int) [256]int {
+	var res [256]int
+	total := 0
+	for level, count := range histogram {
+		total += count
+		res[level] = total
+	}
+	return res
+}
+
+type HistogramStats struct {
+	Count  int
+	Mean   float64
+	StdDev float64
+	// Median, Min and Max are levels; Min and Max are the darkest and
+	// brightest levels present.
+	Median int
+	Min    int
+	Max    int
+}
+
+func Stats(histogram [256]int) HistogramStats {
+	var stats HistogramStats
+	var sum, sumSquares float64
+
+	stats.Min = -1
+	for level, count := range histogram {
+		if count == 0 {
+			continue
+		}
+		if stats.Min < 0 {
+			stats.Min = level
+		}
+		stats.Max = level
+		stats.Count += count
+		sum += float64(level * count)
+		sumSquares += float64(level * level * count)
+	}
+
+	if stats.Count == 0 {
+		return HistogramStats{}
+	}
+
+	n := float64(stats.Count)
+	stats.Mean = sum / n
+	stats.StdDev = math.Sqrt(max(sumSquares/n-stats.Mean*stats.Mean, 0))
+
+	half := (stats.Count + 1) / 2
+	for level, total := range Cumulative(histogram) {
+		if total >= half {
+			stats.Median = level
+			break
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,60 @@
+package filters
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHistogramsGrayLevels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 1))
+	for v := range 256 {
+		img.SetRGBA(v, 0, color.RGBA{uint8(v), uint8(v), uint8(v), 255})
+	}
+
+	histograms := Histograms(img)
+	for _, channel := range []Channel{ChannelRed, ChannelGreen, ChannelBlue, ChannelLuma} {
+		for level, count := range histograms[channel] {
+			if count != 1 {
+				t.Fatalf("%v: level %d counted %d times, want 1", channel, level, count)
+			}
+		}
+	}
+	if histograms[ChannelAlpha][255] != 256 {
+		t.Errorf("alpha 255 counted %d times, want 256", histograms[ChannelAlpha][255])
+	}
+}
+
+func TestHistogramsColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	// Two equal channels used to be counted by the red level alone.
+	img.SetRGBA(0, 0, color.RGBA{10, 10, 200, 255})
+
+	want := LumaLevel(10, 10, 200)
+	if got := Histogram(img); got[want] != 1 {
+		t.Errorf("luminance level %d counted %d times, want 1", want, got[want])
+	}
+}
+
+func TestStats(t *testing.T) {
+	var histogram [256]int
+	histogram[10] = 1
+	histogram[20] = 2
+	histogram[60] = 1
+
+	stats := Stats(histogram)
+	if stats.Count != 4 || stats.Min != 10 || stats.Max != 60 || stats.Median != 20 {
+		t.Errorf("Stats = %+v", stats)
+	}
+	if stats.Mean != 27.5 {
+		t.Errorf("mean %v, want 27.5", stats.Mean)
+	}
+	if want := math.Sqrt((17.5*17.5 + 2*7.5*7.5 + 32.5*32.5) / 4); math.Abs(stats.StdDev-want) > 1e-9 {
+		t.Errorf("std dev %v, want %v", stats.StdDev, want)
+	}
+
+	if empty := Stats([256]int{}); empty != (HistogramStats{}) {
+		t.Errorf("Stats of an empty histogram = %+v", empty)
+	}
+}
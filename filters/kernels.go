@@ -0,0 +1,71 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// KernelPreset is a named kernel together with how it is meant to be run.
+// It is also the JSON format of kernel files.
+type KernelPreset struct {
+	Name   string `json:"name,omitempty"`
+	Kernel Kernel `json:"kernel"`
+	// Gray and Abs mirror the fields of ConvolveOptions.
+	Gray bool `json:"gray,omitempty"`
+	Abs  bool `json:"abs,omitempty"`
+}
+
+// Options returns the convolution options of p with the given border mode.
+func (p KernelPreset) Options(border BorderMode) ConvolveOptions {
+	return ConvolveOptions{Border: border, Gray: p.Gray, Abs: p.Abs}
+}
+
+// KernelPresets lists the kernels of the built-in filters.
+func KernelPresets() []KernelPreset {
+	var presets []KernelPreset
+	for i, name := range freqKernelNames {
+		presets = append(presets, KernelPreset{Name: "Low freq " + name, Kernel: lowFreqKernels[i]})
+	}
+	for i, name := range freqKernelNames {
+		presets = append(presets, KernelPreset{Name: "High freq " + name, Kernel: highFreqKernels[i]})
+	}
+	for i, name := range shiftDirectionNames {
+		presets = append(presets, KernelPreset{
+			Name:   "Shift edge " + name,
+			Kernel: shiftEdgeKernels[i],
+			Gray:   true,
+			Abs:    ShiftDirection(i) == ShiftDiagonal,
+		})
+	}
+	for i, name := range embossDirectionNames {
+		presets = append(presets, KernelPreset{Name: "Embossing " + name, Kernel: embossingKernels[i], Gray: true})
+	}
+	presets = append(presets,
+		KernelPreset{Name: "Edge empower", Kernel: edgeEmpowerKernel, Gray: true, Abs: true},
+		KernelPreset{Name: "Gauss 5x5", Kernel: GaussKernel(5)},
+		KernelPreset{Name: "Sobel X", Kernel: sobelKernels[0], Gray: true, Abs: true},
+		KernelPreset{Name: "Sobel Y", Kernel: sobelKernels[1], Gray: true, Abs: true},
+	)
+	return presets
+}
+
+func ReadKernelPreset(r io.Reader) (KernelPreset, error) {
+	var preset KernelPreset
+	if err := json.NewDecoder(r).Decode(&preset); err != nil {
+		return KernelPreset{}, fmt.Errorf("filters: decode kernel: %w", err)
+	}
+	if err := preset.Kernel.Validate(); err != nil {
+		return KernelPreset{}, err
+	}
+	return preset, nil
+}
+
+func WriteKernelPreset(w io.Writer, preset KernelPreset) error {
+	if err := preset.Kernel.Validate(); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(preset)
+}
@@ -0,0 +1,122 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"os"
+
+	"photoshop/engine"
+	"photoshop/lut"
+)
+
+// newLUTFilter registers f as a pure colour mapping: applying it runs the
+// lookup table that table builds from the parameters.
+func newLUTFilter(f basicFilter, table func(p Params) (lut.LUT, error)) *lutFilter {
+	f.apply = func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+		l, err := table(p)
+		if err != nil {
+			return nil, err
+		}
+		return ApplyLUT(ctx, src, l)
+	}
+	return &lutFilter{basicFilter: f, table: table}
+}
+
+func unitByte(v float64) uint8 {
+	return uint8(math.Round(checkForLimit(v * 255)))
+}
+
+// ApplyLUT maps the colours of src through l, keeping alpha. Per-channel
+// LUTs are compiled into 8-bit tables first; the others are evaluated for
+// every pixel.
+func ApplyLUT(ctx context.Context, src image.Image, l lut.LUT) (image.Image, error) {
+	if tables, ok := lut.Tables8(l); ok {
+		return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+			return tables[0][r], tables[1][g], tables[2][b], a
+		})
+	}
+
+	return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+		outR, outG, outB := l.Apply(float64(r)/255, float64(g)/255, float64(b)/255)
+		return unitByte(outR), unitByte(outG), unitByte(outB), a
+	})
+}
+
+// ChainStep is one filter of a LUT chain with its parameters.
+type ChainStep struct {
+	Filter string
+	Params Params
+}
+
+// ChainLUT composes the lookup tables of steps, applied in order. Every
+// filter has to implement LUTFilter.
+func ChainLUT(steps []ChainStep) (lut.LUT, error) {
+	chain := make(lut.Chain, 0, len(steps))
+	for _, step := range steps {
+		f, ok := Lookup(step.Filter)
+		if !ok {
+			return nil, fmt.Errorf("filters: unknown filter %q", step.Filter)
+		}
+		lf, ok := f.(LUTFilter)
+		if !ok {
+			return nil, fmt.Errorf("filters: %s is not a colour mapping and has no lookup table", f.Title())
+		}
+		l, err := lf.LUT(step.Params)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, l)
+	}
+	return chain, nil
+}
+
+// LoadCube reads a .cube file, using interpolation for its 3D tables.
+func LoadCube(path string, interpolation lut.Interpolation) (*lut.Cube, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cube, err := lut.ReadCube(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	setInterpolation(cube.LUT, interpolation)
+	return cube, nil
+}
+
+func setInterpolation(l lut.LUT, interpolation lut.Interpolation) {
+	switch l := l.(type) {
+	case *lut.LUT3D:
+		l.Interpolation = interpolation
+	case lut.Chain:
+		for _, inner := range l {
+			setInterpolation(inner, interpolation)
+		}
+	}
+}
+
+func init() {
+	Register(newLUTFilter(
+		basicFilter{
+			name: "apply-lut", title: "Apply LUT (.cube)", category: Adjustments,
+			params: []Param{
+				{Name: "file", Label: ".cube file", Kind: ParamText, Default: ""},
+				{Name: "interpolation", Label: "Interpolation (3D)", Kind: ParamChoice, Choices: lut.InterpolationNames(), Default: "Tetrahedral"},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			if p.String("file") == "" {
+				return nil, fmt.Errorf("filters: no .cube file chosen")
+			}
+			cube, err := LoadCube(p.String("file"), lut.Interpolation(p.Index("interpolation", lut.InterpolationNames())))
+			if err != nil {
+				return nil, err
+			}
+			return cube.LUT, nil
+		},
+	))
+}
@@ -0,0 +1,44 @@
+package filters
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChainLUTMatchesFilters(t *testing.T) {
+	src := loadPNG(t, "testdata/fixtures/orig.png")
+	steps := []ChainStep{
+		{Filter: "gamma", Params: Params{"gamma": 0.7}},
+		{Filter: "curves", Params: Params{"rgb": "0,0 64,40 190,220 255,255"}},
+		{Filter: "negative", Params: Params{"ceiling": 100}},
+	}
+
+	want := src
+	for _, step := range steps {
+		f, _ := Lookup(step.Filter)
+		var err error
+		if want, err = f.Apply(context.Background(), want, step.Params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chain, err := ChainLUT(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyLUT(context.Background(), src, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := compareImages(got, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestChainLUTRejectsOtherFilters(t *testing.T) {
+	_, err := ChainLUT([]ChainStep{{Filter: "gamma", Params: Params{"gamma": 2.}}, {Filter: "gauss", Params: Params{"size": 3}}})
+	if err == nil || !strings.Contains(err.Error(), "no lookup table") {
+		t.Errorf("ChainLUT with a blur: %v", err)
+	}
+}
@@ -0,0 +1,395 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	"photoshop/engine"
+)
+
+type ElementShape int
+
+const (
+	ElementRectangle ElementShape = iota
+	// ElementCross is a plus sign one pixel thick through the anchor.
+	ElementCross
+	// ElementEllipse is the ellipse inscribed in the element's box.
+	ElementEllipse
+	// ElementCustom takes the taps from MorphologyParams.Element.
+	ElementCustom
+)
+
+type MorphOperation int
+
+const (
+	MorphErode MorphOperation = iota
+	MorphDilate
+	// MorphOpen erodes, then dilates: it removes specks smaller than the
+	// element.
+	MorphOpen
+	// MorphClose dilates, then erodes: it fills small holes and gaps.
+	MorphClose
+	// MorphGradient is dilation minus erosion, the outline of shapes.
+	MorphGradient
+	// MorphTopHat is the image minus its opening: small bright details.
+	MorphTopHat
+	// MorphBlackHat is the closing minus the image: small dark details.
+	MorphBlackHat
+)
+
+type MorphMode int
+
+const (
+	// MorphBinary thresholds the luminance at 128 first; white is the
+	// foreground.
+	MorphBinary MorphMode = iota
+	// MorphGrayscale takes minima and maxima of every channel.
+	MorphGrayscale
+)
+
+// StructuringElement is the neighbourhood the morphological operations
+// look at, stored row by row. Like a Kernel, its anchor is
+// ((Width-1)/2, (Height-1)/2).
+type StructuringElement struct {
+	Width, Height int
+	Taps          []bool
+}
+
+// maxElementSize caps both sides of predefined and custom elements, and
+// the width and height parameters.
+const maxElementSize = 99
+
+// NewStructuringElement builds a Width x Height element of a predefined
+// shape.
+func NewStructuringElement(shape ElementShape, width, height int) (StructuringElement, error) {
+	if width < 1 || height < 1 || width > maxElementSize || height > maxElementSize {
+		return StructuringElement{}, fmt.Errorf("filters: structuring element size %dx%d out of range [1, %d]", width, height, maxElementSize)
+	}
+
+	e := StructuringElement{Width: width, Height: height, Taps: make([]bool, width*height)}
+	anchor := e.anchor()
+	cx, cy := float64(width-1)/2, float64(height-1)/2
+	rx, ry := float64(width)/2, float64(height)/2
+
+	for y := range height {
+		for x := range width {
+			var on bool
+			switch shape {
+			case ElementRectangle:
+				on = true
+			case ElementCross:
+				on = x == anchor.X || y == anchor.Y
+			case ElementEllipse:
+				dx, dy := (float64(x)-cx)/rx, (float64(y)-cy)/ry
+				on = dx*dx+dy*dy <= 1
+			default:
+				return StructuringElement{}, fmt.Errorf("filters: unknown element shape %d", shape)
+			}
+			e.Taps[y*width+x] = on
+		}
+	}
+	return e, nil
+}
+
+// ParseStructuringElement reads rows of 0 and 1 separated by '/', commas
+// or newlines, e.g. "010/111/010".
+func ParseStructuringElement(text string) (StructuringElement, error) {
+	rows := strings.FieldsFunc(text, func(r rune) bool {
+		return r == '/' || r == ',' || r == '\n' || r == ' '
+	})
+	if len(rows) == 0 {
+		return StructuringElement{}, fmt.Errorf("filters: empty structuring element")
+	}
+
+	e := StructuringElement{Width: len(rows[0]), Height: len(rows)}
+	if e.Width > maxElementSize || e.Height > maxElementSize {
+		return StructuringElement{}, fmt.Errorf("filters: structuring element size %dx%d out of range [1, %d]", e.Width, e.Height, maxElementSize)
+	}
+	for i, row := range rows {
+		if len(row) != e.Width {
+			return StructuringElement{}, fmt.Errorf("filters: structuring element row %d has %d taps, want %d", i+1, len(row), e.Width)
+		}
+		for _, tap := range row {
+			if tap != '0' && tap != '1' {
+				return StructuringElement{}, fmt.Errorf("filters: structuring element tap %q is neither 0 nor 1", tap)
+			}
+			e.Taps = append(e.Taps, tap == '1')
+		}
+	}
+	if len(e.offsets()) == 0 {
+		return StructuringElement{}, fmt.Errorf("filters: structuring element has no taps set")
+	}
+	return e, nil
+}
+
+func (e StructuringElement) String() string {
+	rows := make([]string, e.Height)
+	for y := range e.Height {
+		var b strings.Builder
+		for x := range e.Width {
+			if e.Taps[y*e.Width+x] {
+				b.WriteByte('1')
+			} else {
+				b.WriteByte('0')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return strings.Join(rows, "/")
+}
+
+func (e StructuringElement) anchor() image.Point {
+	return image.Pt((e.Width-1)/2, (e.Height-1)/2)
+}
+
+// offsets lists the set taps relative to the anchor.
+func (e StructuringElement) offsets() []image.Point {
+	anchor := e.anchor()
+	var res []image.Point
+	for y := range e.Height {
+		for x := range e.Width {
+			if e.Taps[y*e.Width+x] {
+				res = append(res, image.Pt(x-anchor.X, y-anchor.Y))
+			}
+		}
+	}
+	return res
+}
+
+// passes splits a full rectangle into a row and a column, which give the
+// same minima and maxima with far fewer taps.
+func (e StructuringElement) passes() [][]image.Point {
+	offsets := e.offsets()
+	if len(offsets) != e.Width*e.Height || e.Width == 1 || e.Height == 1 {
+		return [][]image.Point{offsets}
+	}
+
+	anchor := e.anchor()
+	row := make([]image.Point, e.Width)
+	for x := range row {
+		row[x] = image.Pt(x-anchor.X, 0)
+	}
+	column := make([]image.Point, e.Height)
+	for y := range column {
+		column[y] = image.Pt(0, y-anchor.Y)
+	}
+	return [][]image.Point{row, column}
+}
+
+type MorphologyParams struct {
+	Operation MorphOperation
+	Mode      MorphMode
+	Element   StructuringElement
+	// Iterations repeats the erosions and dilations, e.g. an opening with
+	// two iterations erodes twice and then dilates twice.
+	Iterations int
+}
+
+const maxMorphIterations = 50
+
+func (p MorphologyParams) validate() error {
+	if p.Operation < MorphErode || p.Operation > MorphBlackHat {
+		return fmt.Errorf("filters: unknown morphological operation %d", p.Operation)
+	}
+	if p.Mode != MorphBinary && p.Mode != MorphGrayscale {
+		return fmt.Errorf("filters: unknown morphology mode %d", p.Mode)
+	}
+	if p.Iterations < 1 || p.Iterations > maxMorphIterations {
+		return fmt.Errorf("filters: iterations %d out of range [1, %d]", p.Iterations, maxMorphIterations)
+	}
+	if p.Element.Width*p.Element.Height != len(p.Element.Taps) || len(p.Element.offsets()) == 0 {
+		return fmt.Errorf("filters: invalid structuring element")
+	}
+	return nil
+}
+
+// morphPass replaces every channel value of src with the minimum (erode)
+// or maximum of its neighbours at offsets. Dilation mirrors the offsets so
+// that it is the dual of erosion for asymmetric elements too. Neighbours
+// outside the image are skipped.
+func morphPass(ctx context.Context, src *image.RGBA, offsets []image.Point, erode bool) (*image.RGBA, error) {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	err := engine.Rows(ctx, bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				o := src.PixOffset(x, y)
+
+				var extreme [3]uint8
+				if erode {
+					extreme = [3]uint8{255, 255, 255}
+				}
+
+				for _, offset := range offsets {
+					if !erode {
+						offset = offset.Mul(-1)
+					}
+					pt := image.Pt(x, y).Add(offset)
+					if !pt.In(bounds) {
+						continue
+					}
+
+					i := src.PixOffset(pt.X, pt.Y)
+					for c := range extreme {
+						if erode {
+							extreme[c] = min(extreme[c], src.Pix[i+c])
+						} else {
+							extreme[c] = max(extreme[c], src.Pix[i+c])
+						}
+					}
+				}
+
+				dst.Pix[o], dst.Pix[o+1], dst.Pix[o+2] = extreme[0], extreme[1], extreme[2]
+				dst.Pix[o+3] = src.Pix[o+3]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+// subtract returns a - b per channel, clamped at 0, with the alpha of a.
+func subtract(a, b *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(a.Bounds())
+	for i := 0; i < len(a.Pix); i += 4 {
+		for c := range 3 {
+			dst.Pix[i+c] = a.Pix[i+c] - min(a.Pix[i+c], b.Pix[i+c])
+		}
+		dst.Pix[i+3] = a.Pix[i+3]
+	}
+	return dst
+}
+
+func Morphology(ctx context.Context, src image.Image, params MorphologyParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	var in *image.RGBA
+	if params.Mode == MorphBinary {
+		var err error
+		in, err = engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+			if LumaLevel(r, g, b) < 128 {
+				return 0, 0, 0, a
+			}
+			return 255, 255, 255, a
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		in = engine.ToRGBA(src)
+	}
+
+	// steps lists the erosions (true) and dilations (false) in order, each
+	// applied Iterations times.
+	var steps []bool
+	switch params.Operation {
+	case MorphErode:
+		steps = []bool{true}
+	case MorphDilate:
+		steps = []bool{false}
+	case MorphOpen, MorphTopHat:
+		steps = []bool{true, false}
+	case MorphClose, MorphBlackHat:
+		steps = []bool{false, true}
+	}
+
+	passes := params.Element.passes()
+	total := float64(max(len(steps), 2) * params.Iterations * len(passes))
+	done := 0.
+
+	run := func(img *image.RGBA, erode bool) (*image.RGBA, error) {
+		for range params.Iterations {
+			for _, offsets := range passes {
+				var err error
+				img, err = morphPass(engine.SubProgress(ctx, done/total, (done+1)/total), img, offsets, erode)
+				if err != nil {
+					return nil, err
+				}
+				done++
+			}
+		}
+		return img, nil
+	}
+
+	if params.Operation == MorphGradient {
+		dilated, err := run(in, false)
+		if err != nil {
+			return nil, err
+		}
+		eroded, err := run(in, true)
+		if err != nil {
+			return nil, err
+		}
+		return subtract(dilated, eroded), nil
+	}
+
+	out := in
+	for _, erode := range steps {
+		var err error
+		out, err = run(out, erode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch params.Operation {
+	case MorphTopHat:
+		return subtract(in, out), nil
+	case MorphBlackHat:
+		return subtract(out, in), nil
+	}
+	return out, nil
+}
+
+var (
+	morphOperationNames = []string{"Erode", "Dilate", "Open", "Close", "Gradient", "Top-hat", "Black-hat"}
+	morphModeNames      = []string{"Binary", "Grayscale"}
+	elementShapeNames   = []string{"Rectangle", "Cross", "Ellipse", "Custom"}
+)
+
+var morphologyParams = []Param{
+	{Name: "mode", Label: "Mode", Kind: ParamChoice, Choices: morphModeNames, Default: "Binary"},
+	{Name: "shape", Label: "Element shape", Kind: ParamChoice, Choices: elementShapeNames, Default: "Rectangle"},
+	{Name: "width", Label: "Element width", Kind: ParamInt, Min: 1, Max: maxElementSize, Step: 1, Default: 3, Slider: true},
+	{Name: "height", Label: "Element height", Kind: ParamInt, Min: 1, Max: maxElementSize, Step: 1, Default: 3, Slider: true},
+	{Name: "element", Label: "Custom element (0/1 rows, e.g. 010/111/010)", Kind: ParamText, Default: "010/111/010"},
+	{Name: "iterations", Label: "Iterations", Kind: ParamInt, Min: 1, Max: maxMorphIterations, Step: 1, Default: 1, Slider: true},
+}
+
+func morphologyElement(p Params) (StructuringElement, error) {
+	shape := ElementShape(p.Index("shape", elementShapeNames))
+	if shape == ElementCustom {
+		return ParseStructuringElement(p.String("element"))
+	}
+	return NewStructuringElement(shape, p.Int("width"), p.Int("height"))
+}
+
+func init() {
+	for i, title := range morphOperationNames {
+		operation := MorphOperation(i)
+		Register(&basicFilter{
+			name: strings.ToLower(title), title: title, category: MorphologyOps,
+			params: morphologyParams,
+			apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+				element, err := morphologyElement(p)
+				if err != nil {
+					return nil, err
+				}
+				return Morphology(ctx, src, MorphologyParams{
+					Operation:  operation,
+					Mode:       MorphMode(p.Index("mode", morphModeNames)),
+					Element:    element,
+					Iterations: p.Int("iterations"),
+				})
+			},
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"strings"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestStructuringElementShapes(t *testing.T) {
+	for _, tc := range []struct {
+		shape         ElementShape
+		width, height int
+		want          string
+	}{
+		{ElementRectangle, 3, 2, "111/111"},
+		{ElementCross, 3, 3, "010/111/010"},
+		{ElementEllipse, 5, 5, "01110/11111/11111/11111/01110"},
+	} {
+		e, err := NewStructuringElement(tc.shape, tc.width, tc.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.String(); got != tc.want {
+			t.Errorf("shape %d %dx%d: %s, want %s", tc.shape, tc.width, tc.height, got, tc.want)
+		}
+	}
+}
+
+func TestParseStructuringElement(t *testing.T) {
+	e, err := ParseStructuringElement("010, 111, 010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != "010/111/010" {
+		t.Errorf("parsed %s", e)
+	}
+
+	for _, text := range []string{"", "01/1", "012", "000/000"} {
+		if _, err := ParseStructuringElement(text); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+func TestElementSizeLimits(t *testing.T) {
+	// The dialog sliders reach exactly as far as a custom element may.
+	f, _ := Lookup("dilate")
+	for _, param := range f.Params() {
+		if (param.Name == "width" || param.Name == "height") && param.Max != maxElementSize {
+			t.Errorf("%s: max %v, want %d", param.Name, param.Max, maxElementSize)
+		}
+	}
+
+	if _, err := NewStructuringElement(ElementEllipse, maxElementSize, maxElementSize); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewStructuringElement(ElementRectangle, maxElementSize+1, 1); err == nil {
+		t.Error("built an element wider than the limit")
+	}
+	row := strings.Repeat("1", maxElementSize+1)
+	if _, err := ParseStructuringElement(row); err == nil {
+		t.Error("parsed a custom element wider than the limit")
+	}
+}
+
+// binaryMask draws the points of a 9x9 image white on black.
+func binaryMask(points ...image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for i := range img.Pix {
+		img.Pix[i] = 0
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	for _, pt := range points {
+		setGray(img, pt.X, pt.Y, 255)
+	}
+	return img
+}
+
+func whitePixels(img image.Image) int {
+	rgba := engine.ToRGBA(img)
+	n := 0
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		if rgba.Pix[i] == 255 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMorphologyBinary(t *testing.T) {
+	square, _ := NewStructuringElement(ElementRectangle, 3, 3)
+	ctx := context.Background()
+
+	// A 3x3 block with a speck far away.
+	var points []image.Point
+	for y := 2; y < 5; y++ {
+		for x := 2; x < 5; x++ {
+			points = append(points, image.Pt(x, y))
+		}
+	}
+	points = append(points, image.Pt(7, 7))
+	src := binaryMask(points...)
+
+	for _, tc := range []struct {
+		operation MorphOperation
+		want      int
+	}{
+		{MorphErode, 1},
+		{MorphDilate, 25 + 9},
+		{MorphOpen, 9},
+		{MorphTopHat, 1},
+		{MorphGradient, 25 + 9 - 1},
+	} {
+		got, err := Morphology(ctx, src, MorphologyParams{Operation: tc.operation, Element: square, Iterations: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := whitePixels(got); n != tc.want {
+			t.Errorf("operation %d: %d white pixels, want %d", tc.operation, n, tc.want)
+		}
+	}
+}
+
+func TestMorphologyRectanglePasses(t *testing.T) {
+	// The row and column passes of a rectangle must match its full taps.
+	src := engine.ToRGBA(loadPNG(t, "testdata/fixtures/pattern.png"))
+	e, _ := NewStructuringElement(ElementRectangle, 5, 3)
+
+	full := e.offsets()
+	want, err := morphPass(context.Background(), src, full, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := src
+	for _, offsets := range e.passes() {
+		got, _ = morphPass(context.Background(), got, offsets, true)
+	}
+	if diff := compareImages(got, want); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,564 @@
+package filters
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"sync"
+
+	"photoshop/engine"
+)
+
+type PaletteMethod int
+
+const (
+	// PaletteMedianCut splits the colour box with the widest channel range
+	// at its median until there are enough boxes.
+	PaletteMedianCut PaletteMethod = iota
+	// PaletteOctree files the colours into an octree of their bits and
+	// merges the smallest branches.
+	PaletteOctree
+	// PaletteKMeans refines the median cut palette with Lloyd iterations,
+	// which is slower but minimises the squared error.
+	PaletteKMeans
+)
+
+var paletteMethodNames = []string{"Median cut", "Octree", "K-means"}
+
+func (m PaletteMethod) String() string {
+	if m < 0 || int(m) >= len(paletteMethodNames) {
+		return fmt.Sprintf("PaletteMethod(%d)", int(m))
+	}
+	return paletteMethodNames[m]
+}
+
+type PaletteParams struct {
+	// Colors is the palette size; a transparent entry for fully
+	// transparent pixels comes on top of it, within the 256 of indexed
+	// formats.
+	Colors int
+	Method PaletteMethod
+	Dither DitherMethod
+}
+
+const (
+	maxPaletteColors = 256
+	// paletteBits is the precision colours are bucketed at before a
+	// palette is built, which bounds the work on photos.
+	paletteBits = 6
+	// maxKMeansIterations stops k-means that keeps oscillating.
+	maxKMeansIterations = 16
+)
+
+func (p PaletteParams) validate() error {
+	if p.Colors < 2 || p.Colors > maxPaletteColors {
+		return fmt.Errorf("filters: palette size %d out of range [2, %d]", p.Colors, maxPaletteColors)
+	}
+	if p.Method < PaletteMedianCut || p.Method > PaletteKMeans {
+		return fmt.Errorf("filters: unknown palette method %d", p.Method)
+	}
+	return p.Dither.validate()
+}
+
+// weightedColor is the mean colour of a bucket of pixels and their number.
+type weightedColor struct {
+	c     [3]float64
+	count int
+}
+
+// opaqueColors returns src with straight (not premultiplied) colours and
+// full alpha, and whether any pixel is fully transparent.
+func opaqueColors(src image.Image) (*image.RGBA, bool) {
+	in := engine.ToRGBA(src)
+	bounds := in.Bounds()
+	out := image.NewRGBA(bounds)
+
+	transparent := false
+	for i := 0; i < len(in.Pix); i += 4 {
+		a := int(in.Pix[i+3])
+		switch a {
+		case 0:
+			transparent = true
+		case 255:
+			copy(out.Pix[i:i+3], in.Pix[i:i+3])
+		default:
+			for k := range 3 {
+				out.Pix[i+k] = uint8(min(int(in.Pix[i+k])*255/a, 255))
+			}
+		}
+		out.Pix[i+3] = 255
+	}
+	return out, transparent
+}
+
+// colorBuckets groups the pixels of in, skipping those transparent in
+// mask, by the top paletteBits of every channel.
+func colorBuckets(in, mask *image.RGBA) []weightedColor {
+	const shift = 8 - paletteBits
+
+	sums := make([][4]int, 1<<(3*paletteBits))
+	for i := 0; i < len(in.Pix); i += 4 {
+		if mask.Pix[i+3] == 0 {
+			continue
+		}
+		r, g, b := int(in.Pix[i]), int(in.Pix[i+1]), int(in.Pix[i+2])
+		sum := &sums[(r>>shift)<<(2*paletteBits)|(g>>shift)<<paletteBits|b>>shift]
+		sum[0] += r
+		sum[1] += g
+		sum[2] += b
+		sum[3]++
+	}
+
+	var colors []weightedColor
+	for _, sum := range sums {
+		if n := sum[3]; n > 0 {
+			colors = append(colors, weightedColor{
+				c:     [3]float64{float64(sum[0]) / float64(n), float64(sum[1]) / float64(n), float64(sum[2]) / float64(n)},
+				count: n,
+			})
+		}
+	}
+	return colors
+}
+
+// meanColor returns the mean of colors weighted by their counts.
+func meanColor(colors []weightedColor) [3]float64 {
+	var sum [3]float64
+	total := 0
+	for _, wc := range colors {
+		for k := range sum {
+			sum[k] += wc.c[k] * float64(wc.count)
+		}
+		total += wc.count
+	}
+	for k := range sum {
+		sum[k] /= float64(total)
+	}
+	return sum
+}
+
+func medianCut(colors []weightedColor, n int) [][3]float64 {
+	boxes := [][]weightedColor{colors}
+
+	for len(boxes) < n {
+		// Split the box with the widest channel range, weighted by its
+		// pixel count so that large flat areas get their shades.
+		best, bestChannel, bestScore := -1, 0, 0.
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			lo, hi := box[0].c, box[0].c
+			pixels := 0
+			for _, wc := range box {
+				for k := range lo {
+					lo[k], hi[k] = min(lo[k], wc.c[k]), max(hi[k], wc.c[k])
+				}
+				pixels += wc.count
+			}
+			for k := range lo {
+				if score := (hi[k] - lo[k]) * math.Sqrt(float64(pixels)); score > bestScore {
+					best, bestChannel, bestScore = i, k, score
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		slices.SortFunc(box, func(a, b weightedColor) int {
+			return cmp.Compare(a.c[bestChannel], b.c[bestChannel])
+		})
+
+		half, seen := (sumCounts(box)+1)/2, 0
+		cut := 1
+		for i, wc := range box[:len(box)-1] {
+			seen += wc.count
+			cut = i + 1
+			if seen >= half {
+				break
+			}
+		}
+		boxes = append(boxes, box[cut:])
+		boxes[best] = box[:cut]
+	}
+
+	palette := make([][3]float64, len(boxes))
+	for i, box := range boxes {
+		palette[i] = meanColor(box)
+	}
+	return palette
+}
+
+func sumCounts(colors []weightedColor) int {
+	total := 0
+	for _, wc := range colors {
+		total += wc.count
+	}
+	return total
+}
+
+// octreeNode sums the pixels of its branch; leaves are palette entries.
+type octreeNode struct {
+	children [8]*octreeNode
+	sum      [3]float64
+	count    int
+	leaf     bool
+}
+
+// octree builds the palette of an octree that is reduced, deepest and
+// smallest branches first, until it has at most n leaves.
+func octree(colors []weightedColor, n int) [][3]float64 {
+	const depth = paletteBits
+
+	root := &octreeNode{}
+	// inner[d] holds the nodes at depth d that still have children.
+	var inner [depth][]*octreeNode
+	inner[0] = []*octreeNode{root}
+	leaves := 0
+
+	for _, wc := range colors {
+		node := root
+		for d := range depth {
+			bit := 7 - d
+			index := 0
+			for k := range 3 {
+				index = index<<1 | int(wc.c[k])>>bit&1
+			}
+
+			child := node.children[index]
+			if child == nil {
+				child = &octreeNode{leaf: d == depth-1}
+				node.children[index] = child
+				if child.leaf {
+					leaves++
+				} else {
+					inner[d+1] = append(inner[d+1], child)
+				}
+			}
+
+			node = child
+			for k := range 3 {
+				node.sum[k] += wc.c[k] * float64(wc.count)
+			}
+			node.count += wc.count
+		}
+	}
+
+	for d := depth - 1; d > 0 && leaves > n; d-- {
+		nodes := inner[d]
+		slices.SortFunc(nodes, func(a, b *octreeNode) int { return cmp.Compare(a.count, b.count) })
+		for _, node := range nodes {
+			if leaves <= n {
+				break
+			}
+			// The deeper levels are merged already, so the children are
+			// leaves; the node's sums cover them.
+			children := 0
+			for i, child := range node.children {
+				if child != nil {
+					node.children[i] = nil
+					children++
+				}
+			}
+			node.leaf = true
+			leaves -= children - 1
+		}
+	}
+
+	var entries []weightedColor
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			mean := [3]float64{node.sum[0] / float64(node.count), node.sum[1] / float64(node.count), node.sum[2] / float64(node.count)}
+			entries = append(entries, weightedColor{c: mean, count: node.count})
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+
+	// The first level alone has up to 8 branches; smaller palettes fold
+	// the smallest entry into its nearest neighbour.
+	for len(entries) > n {
+		smallest := 0
+		for i, entry := range entries {
+			if entry.count < entries[smallest].count {
+				smallest = i
+			}
+		}
+		merged := entries[smallest]
+		entries = slices.Delete(entries, smallest, smallest+1)
+
+		nearest := 0
+		for i, entry := range entries {
+			if distance2(entry.c, merged.c) < distance2(entries[nearest].c, merged.c) {
+				nearest = i
+			}
+		}
+		entries[nearest] = weightedColor{c: meanColor([]weightedColor{entries[nearest], merged}), count: entries[nearest].count + merged.count}
+	}
+
+	palette := make([][3]float64, len(entries))
+	for i, entry := range entries {
+		palette[i] = entry.c
+	}
+	return palette
+}
+
+func distance2(a, b [3]float64) float64 {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// paletteSearch finds the nearest palette entry by scanning outwards from
+// the entries with the closest red value, stopping once the red difference
+// alone exceeds the best match.
+type paletteSearch struct {
+	// entries are sorted by red; order holds their palette indices.
+	entries [][3]float64
+	order   []int
+}
+
+func newPaletteSearch(palette [][3]float64) *paletteSearch {
+	s := &paletteSearch{order: make([]int, len(palette))}
+	for i := range s.order {
+		s.order[i] = i
+	}
+	slices.SortFunc(s.order, func(a, b int) int { return cmp.Compare(palette[a][0], palette[b][0]) })
+	s.entries = make([][3]float64, len(palette))
+	for i, index := range s.order {
+		s.entries[i] = palette[index]
+	}
+	return s
+}
+
+// better reports whether entry i at distance d beats the best so far;
+// ties go to the lower palette index, as a plain scan would pick.
+func (s *paletteSearch) better(d float64, i int, bestDistance float64, best int) bool {
+	return d < bestDistance || d == bestDistance && s.order[i] < s.order[best]
+}
+
+func (s *paletteSearch) nearest(c [3]float64) int {
+	start, _ := slices.BinarySearchFunc(s.entries, c[0], func(e [3]float64, red float64) int { return cmp.Compare(e[0], red) })
+
+	best, bestDistance := 0, math.Inf(1)
+	for lo, hi := start-1, start; lo >= 0 || hi < len(s.entries); {
+		if hi < len(s.entries) {
+			if dr := s.entries[hi][0] - c[0]; dr*dr > bestDistance {
+				hi = len(s.entries)
+			} else {
+				if d := distance2(s.entries[hi], c); s.better(d, hi, bestDistance, best) {
+					best, bestDistance = hi, d
+				}
+				hi++
+			}
+		}
+		if lo >= 0 {
+			if dr := c[0] - s.entries[lo][0]; dr*dr > bestDistance {
+				lo = -1
+			} else {
+				if d := distance2(s.entries[lo], c); s.better(d, lo, bestDistance, best) {
+					best, bestDistance = lo, d
+				}
+				lo--
+			}
+		}
+	}
+	return s.order[best]
+}
+
+// kMeans moves the palette to the centroids of the colours nearest to
+// each entry until it settles.
+func kMeans(ctx context.Context, colors []weightedColor, palette [][3]float64) ([][3]float64, error) {
+	for iteration := range maxKMeansIterations {
+		search := newPaletteSearch(palette)
+		var mu sync.Mutex
+		sums := make([][4]float64, len(palette))
+
+		err := engine.Rows(engine.SubProgress(ctx, float64(iteration)/maxKMeansIterations, float64(iteration+1)/maxKMeansIterations),
+			image.Rect(0, 0, 1, len(colors)), func(i0, i1 int) {
+				local := make([][4]float64, len(palette))
+				for _, wc := range colors[i0:i1] {
+					sum := &local[search.nearest(wc.c)]
+					for k := range 3 {
+						sum[k] += wc.c[k] * float64(wc.count)
+					}
+					sum[3] += float64(wc.count)
+				}
+
+				mu.Lock()
+				for i := range sums {
+					for k := range sums[i] {
+						sums[i][k] += local[i][k]
+					}
+				}
+				mu.Unlock()
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		moved := 0.
+		for i, sum := range sums {
+			if sum[3] == 0 {
+				// An entry nobody is nearest to keeps its colour.
+				continue
+			}
+			centroid := [3]float64{sum[0] / sum[3], sum[1] / sum[3], sum[2] / sum[3]}
+			moved = max(moved, distance2(centroid, palette[i]))
+			palette[i] = centroid
+		}
+		if moved < 0.25 {
+			break
+		}
+	}
+	return palette, nil
+}
+
+// BuildPalette picks at most params.Colors opaque colours representing
+// src, sorted from dark to light. Straight colours are used, so
+// semi-transparent pixels count with their unpremultiplied colour.
+func BuildPalette(ctx context.Context, src image.Image, params PaletteParams) (color.Palette, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	opaque, _ := opaqueColors(src)
+	return buildPalette(ctx, opaque, engine.ToRGBA(src), params)
+}
+
+func buildPalette(ctx context.Context, opaque, mask *image.RGBA, params PaletteParams) (color.Palette, error) {
+	colors := colorBuckets(opaque, mask)
+	if len(colors) == 0 {
+		return color.Palette{color.RGBA{0, 0, 0, 255}}, nil
+	}
+
+	var entries [][3]float64
+	switch params.Method {
+	case PaletteMedianCut:
+		entries = medianCut(colors, params.Colors)
+	case PaletteOctree:
+		entries = octree(colors, params.Colors)
+	case PaletteKMeans:
+		var err error
+		if entries, err = kMeans(ctx, colors, medianCut(colors, params.Colors)); err != nil {
+			return nil, err
+		}
+	}
+
+	palette := make(color.Palette, 0, len(entries))
+	seen := map[color.RGBA]bool{}
+	for _, entry := range entries {
+		c := color.RGBA{unitRound(entry[0]), unitRound(entry[1]), unitRound(entry[2]), 255}
+		if !seen[c] {
+			seen[c] = true
+			palette = append(palette, c)
+		}
+	}
+	slices.SortFunc(palette, func(a, b color.Color) int {
+		ca, cb := a.(color.RGBA), b.(color.RGBA)
+		return cmp.Or(
+			cmp.Compare(LumaLevel(ca.R, ca.G, ca.B), LumaLevel(cb.R, cb.G, cb.B)),
+			cmp.Compare(ca.R, cb.R), cmp.Compare(ca.G, cb.G), cmp.Compare(ca.B, cb.B),
+		)
+	})
+	return palette, nil
+}
+
+func unitRound(v float64) uint8 {
+	return uint8(math.Round(checkForLimit(v)))
+}
+
+// ReducePalette maps src onto a palette built by params and returns the
+// indexed image, which PNG and GIF store as such. Fully transparent
+// pixels share an extra transparent entry; the others become opaque.
+func ReducePalette(ctx context.Context, src image.Image, params PaletteParams) (*image.Paletted, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	mask := engine.ToRGBA(src)
+	opaque, transparent := opaqueColors(mask)
+	if transparent {
+		params.Colors = min(params.Colors, maxPaletteColors-1)
+	}
+
+	palette, err := buildPalette(engine.SubProgress(ctx, 0, 0.5), opaque, mask, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([][3]float64, len(palette))
+	index := make(map[[3]uint8]uint8, len(palette))
+	for i, c := range palette {
+		rgba := c.(color.RGBA)
+		entries[i] = [3]float64{float64(rgba.R), float64(rgba.G), float64(rgba.B)}
+		index[[3]uint8{rgba.R, rgba.G, rgba.B}] = uint8(i)
+	}
+
+	search := newPaletteSearch(entries)
+	quantize := func(c [3]float64) [3]uint8 {
+		p := palette[search.nearest(c)].(color.RGBA)
+		return [3]uint8{p.R, p.G, p.B}
+	}
+	// Ordered dithering spreads each value over about the distance between
+	// neighbouring entries of an evenly filled colour cube.
+	spread := 256 / math.Cbrt(float64(len(palette)))
+
+	dithered, err := dither(engine.SubProgress(ctx, 0.5, 1), opaque, params.Dither, spread, quantize)
+	if err != nil {
+		return nil, err
+	}
+	mapped := dithered.(*image.RGBA)
+
+	transparentIndex := uint8(len(palette))
+	if transparent {
+		palette = append(palette, color.RGBA{})
+	}
+
+	bounds := mask.Bounds()
+	dst := image.NewPaletted(bounds, palette)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		i := mask.PixOffset(bounds.Min.X, y)
+		o := dst.PixOffset(bounds.Min.X, y)
+		for x := bounds.Min.X; x < bounds.Max.X; x, i, o = x+1, i+4, o+1 {
+			if mask.Pix[i+3] == 0 {
+				dst.Pix[o] = transparentIndex
+				continue
+			}
+			dst.Pix[o] = index[[3]uint8{mapped.Pix[i], mapped.Pix[i+1], mapped.Pix[i+2]}]
+		}
+	}
+	return dst, nil
+}
+
+func paletteParams(p Params) PaletteParams {
+	return PaletteParams{
+		Colors: p.Int("colors"),
+		Method: PaletteMethod(p.Index("method", paletteMethodNames)),
+		Dither: DitherMethod(p.Index("dither", ditherMethodNames)),
+	}
+}
+
+func init() {
+	Register(&basicFilter{
+		name: "palette", title: "Reduce to palette", category: Adjustments,
+		params: []Param{
+			{Name: "colors", Label: "Colours", Kind: ParamInt, Min: 2, Max: maxPaletteColors, Step: 1, Default: 16, Slider: true},
+			{Name: "method", Label: "Method", Kind: ParamChoice, Choices: paletteMethodNames, Default: "Median cut"},
+			{Name: "dither", Label: "Dithering", Kind: ParamChoice, Choices: ditherMethodNames, Default: "None"},
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return ReducePalette(ctx, src, paletteParams(p))
+		},
+	})
+}
@@ -0,0 +1,125 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// blocks returns an image made of a few flat colours.
+func blocks(colors ...color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 8*len(colors), 8))
+	for i, c := range colors {
+		for y := range 8 {
+			for x := range 8 {
+				img.SetRGBA(8*i+x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func TestReducePaletteKeepsFewColors(t *testing.T) {
+	colors := []color.RGBA{{0, 0, 0, 255}, {250, 10, 10, 255}, {20, 200, 30, 255}, {255, 255, 255, 255}}
+	src := blocks(colors...)
+
+	for method := range PaletteMethod(len(paletteMethodNames)) {
+		res, err := ReducePalette(context.Background(), src, PaletteParams{Colors: 8, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Palette) != len(colors) {
+			t.Errorf("%v: %d palette entries for %d colours", method, len(res.Palette), len(colors))
+		}
+		if diff := compareImages(res, src); diff != "" {
+			t.Errorf("%v: %s", method, diff)
+		}
+	}
+}
+
+func TestBuildPaletteSize(t *testing.T) {
+	src := loadPNG(t, "testdata/fixtures/orig.png")
+	for method := range PaletteMethod(len(paletteMethodNames)) {
+		for _, n := range []int{2, 5, 16, 256} {
+			palette, err := BuildPalette(context.Background(), src, PaletteParams{Colors: n, Method: method})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(palette) < 2 || len(palette) > n {
+				t.Errorf("%v: %d entries, want 2..%d", method, len(palette), n)
+			}
+		}
+	}
+}
+
+// squaredError sums the squared RGB differences of two images.
+func squaredError(a, b image.Image) float64 {
+	total := 0.
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, _ := a.At(x, y).RGBA()
+			r2, g2, b2, _ := b.At(x, y).RGBA()
+			for _, d := range []float64{float64(r1>>8) - float64(r2>>8), float64(g1>>8) - float64(g2>>8), float64(b1>>8) - float64(b2>>8)} {
+				total += d * d
+			}
+		}
+	}
+	return total
+}
+
+func TestKMeansRefinesMedianCut(t *testing.T) {
+	src := loadPNG(t, "testdata/fixtures/orig.png")
+
+	errs := map[PaletteMethod]float64{}
+	for _, method := range []PaletteMethod{PaletteMedianCut, PaletteKMeans} {
+		res, err := ReducePalette(context.Background(), src, PaletteParams{Colors: 8, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs[method] = squaredError(src, res)
+	}
+	if errs[PaletteKMeans] > errs[PaletteMedianCut] {
+		t.Errorf("k-means error %v is above the median cut it starts from (%v)", errs[PaletteKMeans], errs[PaletteMedianCut])
+	}
+}
+
+func TestReducePaletteTransparency(t *testing.T) {
+	src := blocks(color.RGBA{}, color.RGBA{200, 100, 50, 255})
+	res, err := ReducePalette(context.Background(), src, PaletteParams{Colors: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := res.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("transparent pixel became %v", res.At(0, 0))
+	}
+	if got := res.At(8, 0); got != (color.RGBA{200, 100, 50, 255}) {
+		t.Errorf("opaque pixel became %v", got)
+	}
+}
+
+func TestPaletteSearchMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	palette := make([][3]float64, 40)
+	for i := range palette {
+		// Few distinct values make ties common.
+		palette[i] = [3]float64{float64(rng.Intn(8) * 32), float64(rng.Intn(8) * 32), float64(rng.Intn(8) * 32)}
+	}
+
+	search := newPaletteSearch(palette)
+	for range 10000 {
+		c := [3]float64{float64(rng.Intn(256)), float64(rng.Intn(256)), float64(rng.Intn(256))}
+
+		want := 0
+		for i := range palette {
+			if distance2(palette[i], c) < distance2(palette[want], c) {
+				want = i
+			}
+		}
+		if got := search.nearest(c); got != want {
+			t.Fatalf("nearest(%v) = %d %v, want %d %v", c, got, palette[got], want, palette[want])
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/engine"
+	"photoshop/lut"
+)
+
+type NegativeParams struct {
+	// Ceiling is the channel value from which inversion starts.
+	Ceiling int
+}
+
+type BrightnessParams struct {
+	Value int
+}
+
+type BinarizationParams struct {
+	Method ThresholdMethod
+	// Threshold is the first level that turns white with ThresholdFixed.
+	Threshold int
+	// Block is the odd side of the neighbourhood the local methods look at.
+	Block int
+	// Offset is subtracted from the thresholds of the adaptive mean and
+	// Gaussian methods (their C); Niblack and Sauvola ignore it.
+	Offset float64
+	// NiblackK and SauvolaK weigh the standard deviation of the block.
+	NiblackK, SauvolaK float64
+}
+
+// ContrastParams holds the Q1 < Q2 pair used by both contrast operations.
+type ContrastParams struct {
+	Q1, Q2 int
+}
+
+type GammaParams struct {
+	Gamma float64
+}
+
+type QuantizationParams struct {
+	Quants int
+	Dither DitherMethod
+	// OneBit turns the image into black and white pixels, ignoring Quants.
+	OneBit bool
+}
+
+type SolarizationParams struct {
+	Coefficient float64
+}
+
+func checkByte(name string, value int) error {
+	if value < 0 || value > 255 {
+		return fmt.Errorf("filters: %s %d out of range [0, 255]", name, value)
+	}
+	return nil
+}
+
+func (p ContrastParams) validate() error {
+	if err := checkByte("Q1", p.Q1); err != nil {
+		return err
+	}
+	if err := checkByte("Q2", p.Q2); err != nil {
+		return err
+	}
+	if p.Q1 >= p.Q2 {
+		return fmt.Errorf("filters: Q1 (%d) must be less than Q2 (%d)", p.Q1, p.Q2)
+	}
+	return nil
+}
+
+func (p QuantizationParams) validate() error {
+	if !p.OneBit {
+		if _, err := quantizationTable(p.Quants); err != nil {
+			return err
+		}
+	}
+	return p.Dither.validate()
+}
+
+func Grayscale(ctx context.Context, src image.Image) (image.Image, error) {
+	return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+		grayScale := uint8(Luminance(uint32(r), uint32(g), uint32(b)))
+
+		return grayScale, grayScale, grayScale, a
+	})
+}
+
+func NegativeLUT(params NegativeParams) (*lut.LUT1D, error) {
+	if err := checkByte("negative ceiling", params.Ceiling); err != nil {
+		return nil, err
+	}
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(v)
+		if v >= params.Ceiling {
+			table[v] = uint8(255 - v)
+		}
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func Negative(ctx context.Context, src image.Image, params NegativeParams) (image.Image, error) {
+	table, err := NegativeLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+func BrightnessLUT(params BrightnessParams) (*lut.LUT1D, error) {
+	if err := checkByte("brightness", params.Value); err != nil {
+		return nil, err
+	}
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(min(v+params.Value, 255))
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func Brightness(ctx context.Context, src image.Image, params BrightnessParams) (image.Image, error) {
+	table, err := BrightnessLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+func Binarization(ctx context.Context, src image.Image, params BinarizationParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	if !params.Method.local() {
+		// A fixed threshold truncates like Grayscale, so that a level picked
+		// on the grayscale image splits it the same way; Otsu compares the
+		// levels of the histogram it was computed from.
+		threshold, level := params.Threshold, func(r, g, b uint8) uint8 {
+			return uint8(Luminance(uint32(r), uint32(g), uint32(b)))
+		}
+		if params.Method == ThresholdOtsu {
+			threshold, level = OtsuThreshold(Histogram(src)), LumaLevel
+		}
+
+		return engine.Map(ctx, src, func(r, g, b, a uint8) (uint8, uint8, uint8, uint8) {
+			if int(level(r, g, b)) < threshold {
+				return 0, 0, 0, a
+			}
+			return 255, 255, 255, a
+		})
+	}
+
+	in := engine.ToRGBA(src)
+	bounds := in.Bounds()
+	levels := lumaLevels(in)
+
+	thresholds, err := localThresholds(engine.SubProgress(ctx, 0, 0.8), levels, params)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(bounds)
+
+	err = engine.Rows(engine.SubProgress(ctx, 0.8, 1), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			i := in.PixOffset(bounds.Min.X, y)
+			o := levels.Offset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, i, o = x+1, i+4, o+1 {
+				var value uint8
+				if levels.Pix[o] > thresholds.Pix[o] {
+					value = 255
+				}
+				dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = value, value, value, in.Pix[i+3]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+func binarizationParams(p Params) BinarizationParams {
+	return BinarizationParams{
+		Method:    ThresholdMethod(p.Index("method", thresholdMethodNames)),
+		Threshold: p.Int("threshold"),
+		Block:     p.Int("block"),
+		Offset:    p.Float("c"),
+		NiblackK:  p.Float("niblack-k"),
+		SauvolaK:  p.Float("sauvola-k"),
+	}
+}
+
+func IncreaseContrastLUT(params ContrastParams) (*lut.LUT1D, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	coefficient := 255. / float64(params.Q2-params.Q1)
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(checkForLimit(float64(v-params.Q1) * coefficient))
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func IncreaseContrast(ctx context.Context, src image.Image, params ContrastParams) (image.Image, error) {
+	table, err := IncreaseContrastLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+func DecreaseContrastLUT(params ContrastParams) (*lut.LUT1D, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	span := params.Q2 - params.Q1
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(params.Q1 + (v*span)/255)
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func DecreaseContrast(ctx context.Context, src image.Image, params ContrastParams) (image.Image, error) {
+	table, err := DecreaseContrastLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+func GammaLUT(params GammaParams) (*lut.LUT1D, error) {
+	if params.Gamma <= 0 || params.Gamma > 255 {
+		return nil, fmt.Errorf("filters: gamma %g out of range (0, 255]", params.Gamma)
+	}
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(checkForLimit(255. * math.Pow(float64(v)/255., params.Gamma)))
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func Gamma(ctx context.Context, src image.Image, params GammaParams) (image.Image, error) {
+	table, err := GammaLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+// quantizationTable maps every level to the top of its band.
+func quantizationTable(quants int) (*[256]uint8, error) {
+	if quants <= 0 || quants > 255 {
+		return nil, fmt.Errorf("filters: quants %d out of range [1, 255]", quants)
+	}
+
+	var quantsArray [256]uint8
+
+	quantsSize := int(math.Ceil(256. / float64(quants)))
+
+	for i := range quants {
+		value := uint8(checkForLimit(float64(quantsSize - 1 + quantsSize*i)))
+
+		start_point := quantsSize * i
+		end_point := min(quantsSize+quantsSize*i, 256)
+
+		for i := start_point; i < end_point; i++ {
+			quantsArray[i] = value
+		}
+	}
+
+	return &quantsArray, nil
+}
+
+// QuantizationLUT returns the table of undithered per-channel
+// quantization; dithering depends on the neighbouring pixels and has none.
+func QuantizationLUT(params QuantizationParams) (*lut.LUT1D, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	if params.Dither != DitherNone || params.OneBit {
+		return nil, fmt.Errorf("filters: dithered quantization depends on neighbouring pixels and has no lookup table")
+	}
+
+	table, err := quantizationTable(params.Quants)
+	if err != nil {
+		return nil, err
+	}
+	return lut.FromTable(table), nil
+}
+
+// Quantization reduces every channel to Quants levels. With dithering
+// each value goes to the nearest level instead of the top of its band and
+// the difference is spread over the neighbours; OneBit dithers the
+// brightness to black and white.
+func Quantization(ctx context.Context, src image.Image, params QuantizationParams) (image.Image, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	if params.OneBit {
+		gray, err := Grayscale(engine.SubProgress(ctx, 0, 0.2), src)
+		if err != nil {
+			return nil, err
+		}
+		return dither(engine.SubProgress(ctx, 0.2, 1), gray, params.Dither, 255, levelQuantizer([]uint8{0, 255}))
+	}
+
+	table, err := quantizationTable(params.Quants)
+	if err != nil {
+		return nil, err
+	}
+	if params.Dither == DitherNone {
+		return ApplyLUT(ctx, src, lut.FromTable(table))
+	}
+
+	var levels []uint8
+	for level, value := range table {
+		if level == 0 || value != table[level-1] {
+			levels = append(levels, value)
+		}
+	}
+	// Ordered dithering spreads each value over one band.
+	spread := 256. / float64(len(levels))
+	return dither(ctx, src, params.Dither, spread, levelQuantizer(levels))
+}
+
+func SolarizationLUT(params SolarizationParams) (*lut.LUT1D, error) {
+	if params.Coefficient < 0 {
+		return nil, fmt.Errorf("filters: solarization coefficient %g must not be negative", params.Coefficient)
+	}
+
+	var table [256]uint8
+	for v := range 256 {
+		table[v] = uint8(checkForLimit(params.Coefficient * float64(v*(255-v))))
+	}
+
+	return lut.FromTable(&table), nil
+}
+
+func Solarization(ctx context.Context, src image.Image, params SolarizationParams) (image.Image, error) {
+	table, err := SolarizationLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+// quantizationOutputNames are the choices of the quantization output;
+// "1-bit" sets QuantizationParams.OneBit.
+var quantizationOutputNames = []string{"Per channel", "1-bit"}
+
+func quantizationParams(p Params) QuantizationParams {
+	return QuantizationParams{
+		Quants: p.Int("quants"),
+		Dither: DitherMethod(p.Index("dither", ditherMethodNames)),
+		OneBit: p.String("output") == "1-bit",
+	}
+}
+
+func init() {
+	Register(&basicFilter{
+		name: "grayscale", title: "GrayScale", category: PointOps,
+		apply: func(ctx context.Context, src image.Image, _ Params) (image.Image, error) {
+			return Grayscale(ctx, src)
+		},
+	})
+	Register(newLUTFilter(
+		basicFilter{
+			name: "negative", title: "Negative", category: PointOps,
+			params: []Param{
+				{Name: "ceiling", Label: "Ceiling", Kind: ParamInt, Min: 0, Max: 255, Default: 0},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			return NegativeLUT(NegativeParams{Ceiling: p.Int("ceiling")})
+		},
+	))
+	Register(newLUTFilter(
+		basicFilter{
+			name: "brightness", title: "Brightness", category: PointOps,
+			params: []Param{
+				{Name: "value", Label: "Value", Kind: ParamInt, Min: 0, Max: 255, Step: 1, Default: 0, Slider: true},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			return BrightnessLUT(BrightnessParams{Value: p.Int("value")})
+		},
+	))
+	Register(&reportingFilter{
+		basicFilter: basicFilter{
+			name: "binarization", title: "Binarization", category: PointOps,
+			params: []Param{
+				{Name: "method", Label: "Method", Kind: ParamChoice, Choices: thresholdMethodNames, Default: "Fixed"},
+				{Name: "threshold", Label: "Threshold (fixed)", Kind: ParamInt, Min: 0, Max: 255, Step: 1, Default: 0, Slider: true},
+				{Name: "block", Label: "Block size (local)", Kind: ParamInt, Min: 3, Max: 99, Step: 2, Default: 15},
+				{Name: "c", Label: "Constant C (adaptive)", Kind: ParamFloat, Min: -50, Max: 50, Step: 0.5, Default: 5., Slider: true},
+				{Name: "niblack-k", Label: "Niblack k", Kind: ParamFloat, Min: -1, Max: 1, Step: 0.01, Default: -0.2, Slider: true},
+				{Name: "sauvola-k", Label: "Sauvola k", Kind: ParamFloat, Min: 0, Max: 1, Step: 0.01, Default: 0.5, Slider: true},
+			},
+			apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+				return Binarization(ctx, src, binarizationParams(p))
+			},
+		},
+		report: func(ctx context.Context, src image.Image, p Params) (string, error) {
+			return BinarizationReport(ctx, src, binarizationParams(p))
+		},
+	})
+
+	contrastParams := []Param{
+		{Name: "q2", Label: "Q2", Kind: ParamInt, Min: 0, Max: 255},
+		{Name: "q1", Label: "Q1", Kind: ParamInt, Min: 0, Max: 255},
+	}
+	Register(newLUTFilter(
+		basicFilter{
+			name: "increase-contrast", title: "Contrast+", category: PointOps,
+			params: contrastParams,
+		},
+		func(p Params) (lut.LUT, error) {
+			return IncreaseContrastLUT(ContrastParams{Q1: p.Int("q1"), Q2: p.Int("q2")})
+		},
+	))
+	Register(newLUTFilter(
+		basicFilter{
+			name: "decrease-contrast", title: "Contrast-", category: PointOps,
+			params: contrastParams,
+		},
+		func(p Params) (lut.LUT, error) {
+			return DecreaseContrastLUT(ContrastParams{Q1: p.Int("q1"), Q2: p.Int("q2")})
+		},
+	))
+	Register(newLUTFilter(
+		basicFilter{
+			name: "gamma", title: "Gamma conversion", category: PointOps,
+			params: []Param{
+				{Name: "gamma", Label: "Gamma", Kind: ParamFloat, Min: 0, Max: 255, Default: 1.},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			return GammaLUT(GammaParams{Gamma: p.Float("gamma")})
+		},
+	))
+	Register(&lutFilter{
+		basicFilter: basicFilter{
+			name: "quantization", title: "Quantization", category: PointOps,
+			params: []Param{
+				{Name: "quants", Label: "Quants value", Kind: ParamInt, Min: 1, Max: 255, Step: 1, Default: 1, Slider: true},
+				{Name: "dither", Label: "Dithering", Kind: ParamChoice, Choices: ditherMethodNames, Default: "None"},
+				{Name: "output", Label: "Output", Kind: ParamChoice, Choices: quantizationOutputNames, Default: "Per channel"},
+			},
+			apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+				return Quantization(ctx, src, quantizationParams(p))
+			},
+		},
+		table: func(p Params) (lut.LUT, error) {
+			return QuantizationLUT(quantizationParams(p))
+		},
+	})
+	Register(newLUTFilter(
+		basicFilter{
+			name: "solarization", title: "Solarization", category: PointOps,
+			params: []Param{
+				{Name: "coefficient", Label: "Value", Kind: ParamFloat, Min: 0, Max: 0.05, Step: .00001, Default: 4. / 255., Slider: true},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			return SolarizationLUT(SolarizationParams{Coefficient: p.Float("coefficient")})
+		},
+	))
+}
@@ -0,0 +1,226 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"slices"
+
+	"photoshop/engine"
+)
+
+// FreqKernel selects one of the three fixed 3x3 masks of the low and high
+// frequency filters.
+type FreqKernel int
+
+const (
+	KernelH1 FreqKernel = iota
+	KernelH2
+	KernelH3
+)
+
+var lowFreqKernels = [3]Kernel{
+	{Width: 3, Height: 3, Divisor: 9, Data: []float64{
+		1, 1, 1,
+		1, 1, 1,
+		1, 1, 1,
+	}},
+	{Width: 3, Height: 3, Divisor: 10, Data: []float64{
+		1, 1, 1,
+		1, 2, 1,
+		1, 1, 1,
+	}},
+	{Width: 3, Height: 3, Divisor: 16, Data: []float64{
+		1, 2, 1,
+		2, 4, 2,
+		1, 2, 1,
+	}},
+}
+
+var highFreqKernels = [3]Kernel{
+	{Width: 3, Height: 3, Data: []float64{
+		-1, -1, -1,
+		-1, 9, -1,
+		-1, -1, -1,
+	}},
+	{Width: 3, Height: 3, Data: []float64{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	}},
+	{Width: 3, Height: 3, Data: []float64{
+		1, -2, 1,
+		-2, 5, -2,
+		1, -2, 1,
+	}},
+}
+
+type LowFreqParams struct {
+	Kernel FreqKernel
+	Border BorderMode
+}
+
+type HighFreqParams struct {
+	Kernel FreqKernel
+	Border BorderMode
+}
+
+type MedianParams struct {
+	// Size is the odd side of the square window.
+	Size   int
+	Border BorderMode
+}
+
+type GaussBlurParams struct {
+	// Size is the odd side of the Gaussian kernel.
+	Size   int
+	Border BorderMode
+}
+
+func (k FreqKernel) validate() error {
+	if k < KernelH1 || k > KernelH3 {
+		return fmt.Errorf("filters: unknown kernel H%d", int(k)+1)
+	}
+	return nil
+}
+
+func LowFreq(ctx context.Context, src image.Image, params LowFreqParams) (image.Image, error) {
+	if err := params.Kernel.validate(); err != nil {
+		return nil, err
+	}
+
+	return Convolve(ctx, src, lowFreqKernels[params.Kernel], ConvolveOptions{Border: params.Border})
+}
+
+func HighFreq(ctx context.Context, src image.Image, params HighFreqParams) (image.Image, error) {
+	if err := params.Kernel.validate(); err != nil {
+		return nil, err
+	}
+
+	return Convolve(ctx, src, highFreqKernels[params.Kernel], ConvolveOptions{Border: params.Border})
+}
+
+func Median(ctx context.Context, src image.Image, params MedianParams) (image.Image, error) {
+	if params.Size < 1 || params.Size%2 == 0 {
+		return nil, fmt.Errorf("filters: median window size %d must be a positive odd number", params.Size)
+	}
+
+	in := engine.ToRGBA(src)
+
+	radius := params.Size / 2
+	win, err := newWindow(in.Bounds(), params.Size, params.Size, image.Pt(radius, radius), params.Border)
+	if err != nil {
+		return nil, err
+	}
+
+	changedImg := image.NewRGBA(win.out)
+
+	err = engine.Rows(ctx, win.out, func(y0, y1 int) {
+		rowIdx := make([]int, params.Size)
+		r := make([]uint8, 0, params.Size*params.Size)
+		g := make([]uint8, 0, params.Size*params.Size)
+		b := make([]uint8, 0, params.Size*params.Size)
+
+		for y := y0; y < y1; y++ {
+			win.rows(y, rowIdx)
+
+			for x := win.out.Min.X; x < win.out.Max.X; x++ {
+				r, g, b = r[:0], g[:0], b[:0]
+
+				for _, sy := range rowIdx {
+					for _, sx := range win.columns(x) {
+						// Taps outside a constant border count as black.
+						if sy < 0 || sx < 0 {
+							r, g, b = append(r, 0), append(g, 0), append(b, 0)
+							continue
+						}
+						i := in.PixOffset(sx, sy)
+						r = append(r, in.Pix[i])
+						g = append(g, in.Pix[i+1])
+						b = append(b, in.Pix[i+2])
+					}
+				}
+
+				slices.Sort(r)
+				slices.Sort(g)
+				slices.Sort(b)
+
+				medianPos := len(r) / 2
+
+				o := changedImg.PixOffset(x, y)
+				changedImg.Pix[o] = r[medianPos]
+				changedImg.Pix[o+1] = g[medianPos]
+				changedImg.Pix[o+2] = b[medianPos]
+				changedImg.Pix[o+3] = 255
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changedImg, nil
+}
+
+// GaussKernel builds the normalized size x size Gaussian kernel from the
+// binomial coefficients of the matching Pascal's triangle row.
+func GaussKernel(size int) Kernel {
+	kernel := Kernel{Width: size, Height: size, Normalize: true}
+	for _, row := range GaussKernelByPascalRow(pascalRow(size)) {
+		kernel.Data = append(kernel.Data, row...)
+	}
+	return kernel
+}
+
+func GaussBlur(ctx context.Context, src image.Image, params GaussBlurParams) (image.Image, error) {
+	if params.Size < 3 || params.Size%2 == 0 {
+		return nil, fmt.Errorf("filters: gauss window size %d must be an odd number >= 3", params.Size)
+	}
+
+	return Convolve(ctx, src, GaussKernel(params.Size), ConvolveOptions{Border: params.Border})
+}
+
+var freqKernelNames = []string{"H1", "H2", "H3"}
+
+func init() {
+	Register(&basicFilter{
+		name: "lowfreq", title: "Low Freq Filter", category: Smoothing,
+		params: []Param{
+			{Name: "kernel", Label: "Kernel", Kind: ParamChoice, Choices: freqKernelNames, Default: "H1"},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return LowFreq(ctx, src, LowFreqParams{Kernel: FreqKernel(p.Index("kernel", freqKernelNames)), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "highfreq", title: "High Freq Filter", category: Smoothing,
+		params: []Param{
+			{Name: "kernel", Label: "Kernel", Kind: ParamChoice, Choices: freqKernelNames, Default: "H1"},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return HighFreq(ctx, src, HighFreqParams{Kernel: FreqKernel(p.Index("kernel", freqKernelNames)), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "median", title: "Median filter", category: Smoothing,
+		params: []Param{
+			{Name: "size", Label: "Size of window", Kind: ParamInt, Min: 1, Max: 99, Step: 2},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return Median(ctx, src, MedianParams{Size: p.Int("size"), Border: borderOf(p)})
+		},
+	})
+	Register(&basicFilter{
+		name: "gauss", title: "Gauss blur", category: Smoothing,
+		params: []Param{
+			{Name: "size", Label: "Size of window", Kind: ParamInt, Min: 3, Max: 99, Step: 2},
+			borderParam,
+		},
+		apply: func(ctx context.Context, src image.Image, p Params) (image.Image, error) {
+			return GaussBlur(ctx, src, GaussBlurParams{Size: p.Int("size"), Border: borderOf(p)})
+		},
+	})
+}
@@ -0,0 +1,281 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	"photoshop/engine"
+)
+
+type ThresholdMethod int
+
+const (
+	// ThresholdFixed uses BinarizationParams.Threshold as it is.
+	ThresholdFixed ThresholdMethod = iota
+	// ThresholdOtsu picks the global threshold that best separates the
+	// histogram into two classes.
+	ThresholdOtsu
+	// ThresholdMean compares every pixel with the mean of its block.
+	ThresholdMean
+	// ThresholdGaussian compares every pixel with the Gaussian weighted
+	// mean of its block.
+	ThresholdGaussian
+	// ThresholdNiblack uses mean + k*stddev of the block.
+	ThresholdNiblack
+	// ThresholdSauvola uses mean * (1 + k*(stddev/R - 1)) of the block,
+	// which keeps flat backgrounds clean.
+	ThresholdSauvola
+)
+
+var thresholdMethodNames = []string{"Fixed", "Otsu", "Adaptive mean", "Adaptive Gaussian", "Niblack", "Sauvola"}
+
+func (m ThresholdMethod) String() string {
+	if m < 0 || int(m) >= len(thresholdMethodNames) {
+		return fmt.Sprintf("ThresholdMethod(%d)", int(m))
+	}
+	return thresholdMethodNames[m]
+}
+
+// local reports whether the method computes a threshold per pixel.
+func (m ThresholdMethod) local() bool {
+	return m >= ThresholdMean
+}
+
+// sauvolaRange is R in Sauvola's formula: the largest standard deviation
+// 8-bit levels can have.
+const sauvolaRange = 128
+
+func (p BinarizationParams) validate() error {
+	switch {
+	case p.Method < ThresholdFixed || p.Method > ThresholdSauvola:
+		return fmt.Errorf("filters: unknown threshold method %d", p.Method)
+	case p.Method == ThresholdFixed:
+		return checkByte("threshold", p.Threshold)
+	case p.Method.local() && (p.Block < 3 || p.Block%2 == 0):
+		return fmt.Errorf("filters: block size %d must be odd and at least 3", p.Block)
+	}
+	return nil
+}
+
+// OtsuThreshold returns the threshold that maximises the variance between
+// the levels below it and the levels from it on, i.e. the first level that
+// turns white.
+func OtsuThreshold(histogram [256]int) int {
+	total, sum := 0, 0.
+	for level, count := range histogram {
+		total += count
+		sum += float64(level * count)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	best, bestVariance := -1, -1.
+	dark, darkSum := 0, 0.
+	for level := range 255 {
+		dark += histogram[level]
+		darkSum += float64(level * histogram[level])
+
+		light := total - dark
+		if dark == 0 || light == 0 {
+			continue
+		}
+
+		meanDiff := darkSum/float64(dark) - (sum-darkSum)/float64(light)
+		variance := float64(dark) * float64(light) * meanDiff * meanDiff
+		if variance > bestVariance {
+			best, bestVariance = level, variance
+		}
+	}
+
+	if best < 0 {
+		// A single level: keep it white.
+		for level, count := range histogram {
+			if count > 0 {
+				return level
+			}
+		}
+	}
+	return best + 1
+}
+
+// lumaLevels returns the brightness level of every pixel.
+func lumaLevels(src image.Image) *engine.Plane {
+	return engine.PlaneOf(src, func(r, g, b, _ uint8) float64 {
+		return float64(LumaLevel(r, g, b))
+	})
+}
+
+// integral is a summed-area table of a plane and of its squares, with a
+// leading row and column of zeros.
+type integral struct {
+	rect       image.Rectangle
+	stride     int
+	sum, sumSq []float64
+}
+
+func newIntegral(p *engine.Plane) *integral {
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	t := &integral{
+		rect:   p.Rect,
+		stride: w + 1,
+		sum:    make([]float64, (w+1)*(h+1)),
+		sumSq:  make([]float64, (w+1)*(h+1)),
+	}
+
+	for y := range h {
+		rowSum, rowSq := 0., 0.
+		for x := range w {
+			v := p.Pix[y*p.Stride+x]
+			rowSum += v
+			rowSq += v * v
+
+			i := (y+1)*t.stride + x + 1
+			t.sum[i] = t.sum[i-t.stride] + rowSum
+			t.sumSq[i] = t.sumSq[i-t.stride] + rowSq
+		}
+	}
+	return t
+}
+
+// stats returns the mean and standard deviation over r, which must lie
+// inside the plane.
+func (t *integral) stats(r image.Rectangle) (mean, stdDev float64) {
+	x0, y0 := r.Min.X-t.rect.Min.X, r.Min.Y-t.rect.Min.Y
+	x1, y1 := r.Max.X-t.rect.Min.X, r.Max.Y-t.rect.Min.Y
+
+	area := func(s []float64) float64 {
+		return s[y1*t.stride+x1] - s[y0*t.stride+x1] - s[y1*t.stride+x0] + s[y0*t.stride+x0]
+	}
+
+	n := float64(r.Dx() * r.Dy())
+	mean = area(t.sum) / n
+	return mean, math.Sqrt(max(area(t.sumSq)/n-mean*mean, 0))
+}
+
+// binomialBlur smooths p with the binomial (Pascal row) approximation of a
+// Gaussian of the given size, repeating the edge pixels.
+func binomialBlur(ctx context.Context, p *engine.Plane, size int) (*engine.Plane, error) {
+	weights := pascalRow(size)
+	sum := 0.
+	for _, w := range weights {
+		sum += w
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	bounds := p.Rect
+	radius := size / 2
+	horizontal := engine.NewPlane(bounds)
+	out := engine.NewPlane(bounds)
+
+	err := engine.Rows(engine.SubProgress(ctx, 0, 0.5), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				v := 0.
+				for k, w := range weights {
+					v += w * p.At(min(max(x+k-radius, bounds.Min.X), bounds.Max.X-1), y)
+				}
+				horizontal.Pix[horizontal.Offset(x, y)] = v
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = engine.Rows(engine.SubProgress(ctx, 0.5, 1), bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				v := 0.
+				for k, w := range weights {
+					v += w * horizontal.At(x, min(max(y+k-radius, bounds.Min.Y), bounds.Max.Y-1))
+				}
+				out.Pix[out.Offset(x, y)] = v
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// localThresholds computes the threshold of every pixel for the local
+// methods; pixels above their threshold turn white. Blocks are cut off at
+// the image border.
+func localThresholds(ctx context.Context, levels *engine.Plane, params BinarizationParams) (*engine.Plane, error) {
+	if params.Method == ThresholdGaussian {
+		thresholds, err := binomialBlur(ctx, levels, params.Block)
+		if err != nil {
+			return nil, err
+		}
+		for i := range thresholds.Pix {
+			thresholds.Pix[i] -= params.Offset
+		}
+		return thresholds, nil
+	}
+
+	bounds := levels.Rect
+	radius := params.Block / 2
+	table := newIntegral(levels)
+	thresholds := engine.NewPlane(bounds)
+
+	err := engine.Rows(ctx, bounds, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			o := thresholds.Offset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x, o = x+1, o+1 {
+				block := image.Rect(x-radius, y-radius, x+radius+1, y+radius+1).Intersect(bounds)
+				mean, stdDev := table.stats(block)
+
+				threshold := mean - params.Offset
+				switch params.Method {
+				case ThresholdNiblack:
+					threshold = mean + params.NiblackK*stdDev
+				case ThresholdSauvola:
+					threshold = mean * (1 + params.SauvolaK*(stdDev/sauvolaRange-1))
+				}
+				thresholds.Pix[o] = threshold
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return thresholds, nil
+}
+
+// BinarizationReport describes the threshold params lead to on src: the
+// level itself for the global methods and its spread for the local ones.
+func BinarizationReport(ctx context.Context, src image.Image, params BinarizationParams) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+
+	switch params.Method {
+	case ThresholdFixed:
+		return fmt.Sprintf("Threshold: %d", params.Threshold), nil
+	case ThresholdOtsu:
+		return fmt.Sprintf("Otsu threshold: %d", OtsuThreshold(Histogram(src))), nil
+	}
+
+	thresholds, err := localThresholds(ctx, lumaLevels(src), params)
+	if err != nil {
+		return "", err
+	}
+	if len(thresholds.Pix) == 0 {
+		return "Empty image", nil
+	}
+
+	lo, hi, sum := math.Inf(1), math.Inf(-1), 0.
+	for _, t := range thresholds.Pix {
+		lo, hi = min(lo, t), max(hi, t)
+		sum += t
+	}
+	return fmt.Sprintf("Local thresholds: %.1f to %.1f, mean %.1f", lo, hi, sum/float64(len(thresholds.Pix))), nil
+}
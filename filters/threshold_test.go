@@ -0,0 +1,144 @@
+package filters
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"photoshop/engine"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	var histogram [256]int
+	histogram[40] = 100
+	histogram[50] = 50
+	histogram[200] = 80
+	histogram[210] = 120
+
+	if got := OtsuThreshold(histogram); got <= 50 || got > 200 {
+		t.Errorf("threshold %d does not separate the two clusters", got)
+	}
+
+	var single [256]int
+	single[90] = 10
+	if got := OtsuThreshold(single); got != 90 {
+		t.Errorf("single level: threshold %d, want 90", got)
+	}
+}
+
+func TestFixedThresholdMatchesGrayscale(t *testing.T) {
+	// The grid includes colours like (0, 55, 5) whose luminance lands just
+	// below a whole level.
+	img := image.NewRGBA(image.Rect(0, 0, 52, 52*52))
+	for y := range 52 * 52 {
+		for x := range 52 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 5), uint8(y / 52 * 5), uint8(y % 52 * 5), 255})
+		}
+	}
+	gray, err := Grayscale(context.Background(), img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grayImg := engine.ToRGBA(gray)
+
+	for _, threshold := range []int{33, 63, 128, 200} {
+		got, err := Binarization(context.Background(), img, BinarizationParams{Threshold: threshold})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := engine.ToRGBA(got)
+		for i := 0; i < len(out.Pix); i += 4 {
+			if want := int(grayImg.Pix[i]) >= threshold; (out.Pix[i] == 255) != want {
+				t.Fatalf("threshold %d: pixel %v of grey %d gave %d", threshold, img.Pix[i:i+3], grayImg.Pix[i], out.Pix[i])
+			}
+		}
+	}
+}
+
+func TestIntegralStats(t *testing.T) {
+	plane := engine.NewPlane(image.Rect(2, 3, 6, 6))
+	for i := range plane.Pix {
+		plane.Pix[i] = float64(i)
+	}
+	table := newIntegral(plane)
+
+	// The bottom right 2x2 block holds 6, 7, 10 and 11.
+	mean, stdDev := table.stats(image.Rect(4, 4, 6, 6))
+	if mean != 8.5 {
+		t.Errorf("mean %v, want 8.5", mean)
+	}
+	if want := math.Sqrt(4.25); math.Abs(stdDev-want) > 1e-9 {
+		t.Errorf("std dev %v, want %v", stdDev, want)
+	}
+}
+
+func TestLocalThresholdsFlat(t *testing.T) {
+	// A blank page has to stay white. Niblack is left out: without an
+	// offset its threshold equals a flat block's level, the known weakness
+	// Sauvola's formula fixes.
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for i := range img.Pix {
+		img.Pix[i] = 230
+	}
+	img.Set(10, 10, color.RGBA{20, 20, 20, 255})
+
+	for _, method := range []ThresholdMethod{ThresholdMean, ThresholdGaussian, ThresholdSauvola} {
+		params := BinarizationParams{Method: method, Block: 7, Offset: 5, NiblackK: -0.2, SauvolaK: 0.5}
+		got, err := Binarization(context.Background(), img, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := engine.ToRGBA(got)
+		if c := out.RGBAAt(0, 0); c.R != 255 {
+			t.Errorf("%v: background turned %v", method, c)
+		}
+		if c := out.RGBAAt(10, 10); c.R != 0 {
+			t.Errorf("%v: dark pixel turned %v", method, c)
+		}
+	}
+}
+
+func TestOffsetOnlyShiftsAdaptiveMethods(t *testing.T) {
+	levels := engine.NewPlane(image.Rect(0, 0, 9, 9))
+	for i := range levels.Pix {
+		levels.Pix[i] = float64(i * 3 % 256)
+	}
+
+	for method := ThresholdMean; method <= ThresholdSauvola; method++ {
+		params := BinarizationParams{Method: method, Block: 5, NiblackK: -0.2, SauvolaK: 0.5}
+		plain, err := localThresholds(context.Background(), levels, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Offset = 5
+		shifted, err := localThresholds(context.Background(), levels, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := 0.
+		if method == ThresholdMean || method == ThresholdGaussian {
+			want = 5
+		}
+		for i := range plain.Pix {
+			if got := plain.Pix[i] - shifted.Pix[i]; math.Abs(got-want) > 1e-9 {
+				t.Fatalf("%v: offset 5 moved the threshold by %v, want %v", method, got, want)
+			}
+		}
+	}
+}
+
+func TestBinarizationParamsValidate(t *testing.T) {
+	for _, params := range []BinarizationParams{
+		{Method: ThresholdFixed, Threshold: 300},
+		{Method: ThresholdMean, Block: 4},
+		{Method: ThresholdSauvola, Block: 1},
+		{Method: ThresholdSauvola + 1},
+	} {
+		if err := params.validate(); err == nil {
+			t.Errorf("%+v: no error", params)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"photoshop/lut"
+)
+
+// CurvePoint is a control point of a tone curve, mapping input level X to
+// output level Y, both in [0, 255].
+type CurvePoint struct {
+	X, Y float64
+}
+
+// Curve is a tone curve through its control points, interpolated with a
+// monotone cubic spline so that it never overshoots between points. Left
+// of the first and right of the last point the curve stays flat.
+type Curve []CurvePoint
+
+// IdentityCurve maps every level to itself.
+func IdentityCurve() Curve {
+	return Curve{{0, 0}, {255, 255}}
+}
+
+func (c Curve) Validate() error {
+	if len(c) < 2 {
+		return fmt.Errorf("filters: a curve needs at least 2 points, got %d", len(c))
+	}
+	for i, pt := range c {
+		if pt.X < 0 || pt.X > 255 || pt.Y < 0 || pt.Y > 255 {
+			return fmt.Errorf("filters: curve point (%g, %g) out of range [0, 255]", pt.X, pt.Y)
+		}
+		if i > 0 && pt.X <= c[i-1].X {
+			return fmt.Errorf("filters: curve points must have increasing inputs, %g follows %g", pt.X, c[i-1].X)
+		}
+	}
+	return nil
+}
+
+// ParseCurve reads points written as "x,y" pairs separated by spaces, e.g.
+// "0,0 64,40 255,255". The points are sorted by input.
+func ParseCurve(text string) (Curve, error) {
+	var c Curve
+	for _, field := range strings.Fields(text) {
+		xText, yText, ok := strings.Cut(field, ",")
+		if !ok {
+			return nil, fmt.Errorf("filters: curve point %q is not x,y", field)
+		}
+		x, err := strconv.ParseFloat(xText, 64)
+		if err != nil {
+			return nil, fmt.Errorf("filters: curve point %q: %w", field, err)
+		}
+		y, err := strconv.ParseFloat(yText, 64)
+		if err != nil {
+			return nil, fmt.Errorf("filters: curve point %q: %w", field, err)
+		}
+		c = append(c, CurvePoint{x, y})
+	}
+
+	slices.SortFunc(c, func(a, b CurvePoint) int {
+		switch {
+		case a.X < b.X:
+			return -1
+		case a.X > b.X:
+			return 1
+		}
+		return 0
+	})
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c Curve) String() string {
+	fields := make([]string, len(c))
+	for i, pt := range c {
+		fields[i] = strconv.FormatFloat(pt.X, 'f', -1, 64) + "," + strconv.FormatFloat(pt.Y, 'f', -1, 64)
+	}
+	return strings.Join(fields, " ")
+}
+
+// tangents returns the Fritsch-Carlson slopes at the control points.
+func (c Curve) tangents() []float64 {
+	n := len(c)
+	secants := make([]float64, n-1)
+	for k := range secants {
+		secants[k] = (c[k+1].Y - c[k].Y) / (c[k+1].X - c[k].X)
+	}
+
+	m := make([]float64, n)
+	m[0], m[n-1] = secants[0], secants[n-2]
+	for k := 1; k < n-1; k++ {
+		if secants[k-1]*secants[k] > 0 {
+			m[k] = (secants[k-1] + secants[k]) / 2
+		}
+	}
+
+	for k, d := range secants {
+		if d == 0 {
+			m[k], m[k+1] = 0, 0
+			continue
+		}
+		a, b := m[k]/d, m[k+1]/d
+		if s := a*a + b*b; s > 9 {
+			t := 3 / math.Sqrt(s)
+			m[k], m[k+1] = t*a*d, t*b*d
+		}
+	}
+	return m
+}
+
+// Table evaluates the curve at every level. The curve must be valid.
+func (c Curve) Table() [256]uint8 {
+	m := c.tangents()
+	last := len(c) - 1
+
+	var table [256]uint8
+	k := 0
+	for level := range table {
+		x := float64(level)
+
+		var y float64
+		switch {
+		case x <= c[0].X:
+			y = c[0].Y
+		case x >= c[last].X:
+			y = c[last].Y
+		default:
+			for x > c[k+1].X {
+				k++
+			}
+			h := c[k+1].X - c[k].X
+			t := (x - c[k].X) / h
+			t2, t3 := t*t, t*t*t
+
+			y = (2*t3-3*t2+1)*c[k].Y + (t3-2*t2+t)*h*m[k] +
+				(-2*t3+3*t2)*c[k+1].Y + (t3-t2)*h*m[k+1]
+		}
+		table[level] = uint8(math.Round(checkForLimit(y)))
+	}
+	return table
+}
+
+// CurvesParams holds one curve per channel and the composite curve that
+// is applied to all three afterwards.
+type CurvesParams struct {
+	Composite, Red, Green, Blue Curve
+}
+
+func CurvesLUT(params CurvesParams) (*lut.LUT1D, error) {
+	curves := []Curve{params.Composite, params.Red, params.Green, params.Blue}
+	for _, curve := range curves {
+		if err := curve.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	composite := params.Composite.Table()
+	var tables [3][256]uint8
+	for c, curve := range curves[1:] {
+		channel := curve.Table()
+		for level := range 256 {
+			tables[c][level] = composite[channel[level]]
+		}
+	}
+
+	return lut.FromTables(&tables), nil
+}
+
+func Curves(ctx context.Context, src image.Image, params CurvesParams) (image.Image, error) {
+	table, err := CurvesLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+// LevelsParams remaps [InBlack, InWhite] onto [OutBlack, OutWhite] with a
+// gamma correction in between; Gamma above 1 brightens the midtones.
+type LevelsParams struct {
+	InBlack, InWhite   int
+	Gamma              float64
+	OutBlack, OutWhite int
+	// Channel selects the channel to adjust; ChannelLuma means all three.
+	Channel Channel
+}
+
+const (
+	minLevelsGamma = 0.1
+	maxLevelsGamma = 10.
+)
+
+func (p LevelsParams) validate() error {
+	for _, check := range []struct {
+		name  string
+		value int
+	}{{"input black", p.InBlack}, {"input white", p.InWhite}, {"output black", p.OutBlack}, {"output white", p.OutWhite}} {
+		if err := checkByte(check.name, check.value); err != nil {
+			return err
+		}
+	}
+	if p.InBlack >= p.InWhite {
+		return fmt.Errorf("filters: input black (%d) must be less than input white (%d)", p.InBlack, p.InWhite)
+	}
+	if p.Gamma < minLevelsGamma || p.Gamma > maxLevelsGamma {
+		return fmt.Errorf("filters: levels gamma %g out of range [%g, %g]", p.Gamma, minLevelsGamma, maxLevelsGamma)
+	}
+	switch p.Channel {
+	case ChannelRed, ChannelGreen, ChannelBlue, ChannelLuma:
+		return nil
+	}
+	return fmt.Errorf("filters: levels cannot adjust the %v channel", p.Channel)
+}
+
+// Table returns the mapping of the levels adjustment.
+func (p LevelsParams) Table() [256]uint8 {
+	var table [256]uint8
+	span := float64(p.InWhite - p.InBlack)
+	for level := range table {
+		t := min(max(float64(level-p.InBlack)/span, 0), 1)
+		t = math.Pow(t, 1/p.Gamma)
+		table[level] = uint8(math.Round(float64(p.OutBlack) + t*float64(p.OutWhite-p.OutBlack)))
+	}
+	return table
+}
+
+func LevelsLUT(params LevelsParams) (*lut.LUT1D, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	table := params.Table()
+	if params.Channel == ChannelLuma {
+		return lut.FromTable(&table), nil
+	}
+
+	var tables [3][256]uint8
+	for c := range tables {
+		for level := range 256 {
+			tables[c][level] = uint8(level)
+		}
+	}
+	tables[params.Channel] = table
+
+	return lut.FromTables(&tables), nil
+}
+
+func Levels(ctx context.Context, src image.Image, params LevelsParams) (image.Image, error) {
+	table, err := LevelsLUT(params)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyLUT(ctx, src, table)
+}
+
+// CurveParamNames are the parameters of the curves filter, one per curve
+// in the order of CurvesParams.
+var CurveParamNames = []string{"rgb", "red", "green", "blue"}
+
+// levelsChannels are the channel choices of the levels filter; "RGB"
+// stands for ChannelLuma.
+var (
+	levelsChannelNames = []string{"RGB", "Red", "Green", "Blue"}
+	levelsChannels     = []Channel{ChannelLuma, ChannelRed, ChannelGreen, ChannelBlue}
+)
+
+func init() {
+	identity := IdentityCurve().String()
+	curveParams := make([]Param, len(CurveParamNames))
+	for i, label := range []string{"RGB", "Red", "Green", "Blue"} {
+		curveParams[i] = Param{Name: CurveParamNames[i], Label: label + " curve (x,y points)", Kind: ParamText, Default: identity}
+	}
+
+	Register(newLUTFilter(
+		basicFilter{
+			name: "curves", title: "Curves", category: Adjustments,
+			params: curveParams,
+		},
+		func(p Params) (lut.LUT, error) {
+			var curves [4]Curve
+			for i, name := range CurveParamNames {
+				curve, err := ParseCurve(p.String(name))
+				if err != nil {
+					return nil, err
+				}
+				curves[i] = curve
+			}
+			return CurvesLUT(CurvesParams{Composite: curves[0], Red: curves[1], Green: curves[2], Blue: curves[3]})
+		},
+	))
+	Register(newLUTFilter(
+		basicFilter{
+			name: "levels", title: "Levels", category: Adjustments,
+			params: []Param{
+				{Name: "channel", Label: "Channel", Kind: ParamChoice, Choices: levelsChannelNames, Default: "RGB"},
+				{Name: "in-black", Label: "Input black", Kind: ParamInt, Min: 0, Max: 254, Step: 1, Default: 0, Slider: true},
+				{Name: "gamma", Label: "Gamma", Kind: ParamFloat, Min: minLevelsGamma, Max: maxLevelsGamma, Step: 0.01, Default: 1., Slider: true},
+				{Name: "in-white", Label: "Input white", Kind: ParamInt, Min: 1, Max: 255, Step: 1, Default: 255, Slider: true},
+				{Name: "out-black", Label: "Output black", Kind: ParamInt, Min: 0, Max: 255, Step: 1, Default: 0, Slider: true},
+				{Name: "out-white", Label: "Output white", Kind: ParamInt, Min: 0, Max: 255, Step: 1, Default: 255, Slider: true},
+			},
+		},
+		func(p Params) (lut.LUT, error) {
+			return LevelsLUT(levelsParams(p))
+		},
+	))
+}
+
+func levelsParams(p Params) LevelsParams {
+	params := LevelsParams{
+		InBlack:  p.Int("in-black"),
+		InWhite:  p.Int("in-white"),
+		Gamma:    p.Float("gamma"),
+		OutBlack: p.Int("out-black"),
+		OutWhite: p.Int("out-white"),
+		Channel:  ChannelAlpha,
+	}
+	if i := p.Index("channel", levelsChannelNames); i >= 0 {
+		params.Channel = levelsChannels[i]
+	}
+	return params
+}
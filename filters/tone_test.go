@@ -0,0 +1,75 @@
+package filters
+
+import (
+	"testing"
+)
+
+func TestCurveIdentity(t *testing.T) {
+	table := IdentityCurve().Table()
+	for level, value := range table {
+		if int(value) != level {
+			t.Fatalf("identity maps %d to %d", level, value)
+		}
+	}
+}
+
+func TestCurveMonotone(t *testing.T) {
+	// A steep S curve must not overshoot its points or turn back.
+	c, err := ParseCurve("255,255 0,0 100,30 150,230")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := c.Table()
+	if table[0] != 0 || table[100] != 30 || table[150] != 230 || table[255] != 255 {
+		t.Errorf("curve misses its points: %d %d %d %d", table[0], table[100], table[150], table[255])
+	}
+	for level := 1; level < 256; level++ {
+		if table[level] < table[level-1] {
+			t.Fatalf("curve drops from %d to %d at %d", table[level-1], table[level], level)
+		}
+	}
+}
+
+func TestCurveFlatEnds(t *testing.T) {
+	c := Curve{{50, 80}, {200, 180}}
+	table := c.Table()
+	if table[0] != 80 || table[255] != 180 {
+		t.Errorf("ends are %d and %d, want 80 and 180", table[0], table[255])
+	}
+}
+
+func TestParseCurveErrors(t *testing.T) {
+	for _, text := range []string{"", "0,0", "0,0 x,1", "0,0 0,255", "0,0 300,255", "0;0 255,255"} {
+		if _, err := ParseCurve(text); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+
+	c, _ := ParseCurve("0,0 64.5,40 255,255")
+	if got := c.String(); got != "0,0 64.5,40 255,255" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLevelsTable(t *testing.T) {
+	params := LevelsParams{InBlack: 20, InWhite: 220, Gamma: 1, OutBlack: 10, OutWhite: 250, Channel: ChannelLuma}
+	if err := params.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	table := params.Table()
+	if table[0] != 10 || table[20] != 10 || table[220] != 250 || table[255] != 250 || table[120] != 130 {
+		t.Errorf("table gives %d %d %d %d %d", table[0], table[20], table[120], table[220], table[255])
+	}
+
+	params.Gamma = 2
+	if brighter := params.Table(); brighter[120] <= table[120] {
+		t.Errorf("gamma 2 gives %d at 120, not above %d", brighter[120], table[120])
+	}
+
+	params.InWhite = params.InBlack
+	if err := params.validate(); err == nil {
+		t.Error("empty input range accepted")
+	}
+}
@@ -0,0 +1,214 @@
+// Package history keeps the undo/redo stack of applied operations.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"sort"
+	"strings"
+
+	"photoshop/filters"
+)
+
+// Step is one state of the image together with the operation producing it.
+type Step struct {
+	Title string
+	// Filter is the registry name of the applied filter, empty for steps
+	// that are not filters such as opening a file.
+	Filter string
+	Params filters.Params
+	Image  image.Image
+	// Base marks steps that start over from a loaded image, such as
+	// opening a file or going back to the original.
+	Base bool
+}
+
+// Label describes the step and its parameters for the history panel.
+func (s Step) Label() string {
+	if len(s.Params) == 0 {
+		return s.Title
+	}
+
+	names := make([]string, 0, len(s.Params))
+	for name := range s.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%v", name, s.Params[name])
+	}
+	return s.Title + " (" + strings.Join(pairs, ", ") + ")"
+}
+
+// History is a linear undo stack. Pushing after an undo discards the steps
+// that could have been redone.
+type History struct {
+	steps   []Step
+	current int
+	limit   int64
+}
+
+// New creates an empty history keeping at most limit bytes of images;
+// a limit <= 0 disables the cap.
+func New(limit int64) *History {
+	return &History{current: -1, limit: limit}
+}
+
+// Reset drops every step and starts over from initial.
+func (h *History) Reset(initial Step) {
+	h.steps = []Step{initial}
+	h.current = 0
+}
+
+func (h *History) Push(step Step) {
+	h.steps = append(h.steps[:h.current+1], step)
+	h.current = len(h.steps) - 1
+	h.trim()
+}
+
+// SetLimit changes the memory cap, dropping old steps if needed.
+func (h *History) SetLimit(limit int64) {
+	h.limit = limit
+	h.trim()
+}
+
+func (h *History) Limit() int64 {
+	return h.limit
+}
+
+// trim removes the oldest steps until the images fit into the limit. The
+// current step is always kept.
+func (h *History) trim() {
+	if h.limit <= 0 {
+		return
+	}
+
+	for h.current > 0 && h.Size() > h.limit {
+		h.steps[0] = Step{}
+		h.steps = h.steps[1:]
+		h.current--
+	}
+}
+
+// Size estimates the memory held by the images of all steps. An image
+// shared by several steps, e.g. the original restored later, counts once.
+func (h *History) Size() int64 {
+	var size int64
+	seen := make(map[image.Image]bool, len(h.steps))
+	for _, step := range h.steps {
+		if step.Image == nil || seen[step.Image] {
+			continue
+		}
+		seen[step.Image] = true
+		size += imageSize(step.Image)
+	}
+	return size
+}
+
+func imageSize(img image.Image) int64 {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return int64(len(img.Pix))
+	case *image.NRGBA:
+		return int64(len(img.Pix))
+	case *image.RGBA64:
+		return int64(len(img.Pix))
+	case *image.NRGBA64:
+		return int64(len(img.Pix))
+	case *image.Gray:
+		return int64(len(img.Pix))
+	case *image.Gray16:
+		return int64(len(img.Pix))
+	case *image.Paletted:
+		return int64(len(img.Pix))
+	case *image.YCbCr:
+		return int64(len(img.Y) + len(img.Cb) + len(img.Cr))
+	case *image.NYCbCrA:
+		return int64(len(img.Y) + len(img.Cb) + len(img.Cr) + len(img.A))
+	case *image.CMYK:
+		return int64(len(img.Pix))
+	}
+	bounds := img.Bounds()
+	return int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+}
+
+func (h *History) Undo() (Step, bool) {
+	if !h.CanUndo() {
+		return Step{}, false
+	}
+	h.current--
+	return h.steps[h.current], true
+}
+
+func (h *History) Redo() (Step, bool) {
+	if !h.CanRedo() {
+		return Step{}, false
+	}
+	h.current++
+	return h.steps[h.current], true
+}
+
+// JumpTo makes step i current without discarding the others.
+func (h *History) JumpTo(i int) (Step, bool) {
+	if i < 0 || i >= len(h.steps) {
+		return Step{}, false
+	}
+	h.current = i
+	return h.steps[i], true
+}
+
+// FilterSteps returns the steps after the last base step up to the
+// current one, for replaying them as one colour mapping. It fails when the
+// base step was trimmed or a step in between applied no registered filter.
+func (h *History) FilterSteps() ([]Step, error) {
+	base := h.current
+	for base >= 0 && !h.steps[base].Base {
+		base--
+	}
+	if base < 0 {
+		return nil, errors.New("the history no longer reaches the opened image; raise its memory limit")
+	}
+	if base == h.current {
+		return nil, errors.New("no filters applied since the image was opened")
+	}
+
+	steps := h.steps[base+1 : h.current+1]
+	for _, step := range steps {
+		if step.Filter == "" {
+			return nil, fmt.Errorf("%s is not a colour mapping", step.Title)
+		}
+	}
+	return slices.Clone(steps), nil
+}
+
+func (h *History) CanUndo() bool {
+	return h.current > 0
+}
+
+func (h *History) CanRedo() bool {
+	return h.current < len(h.steps)-1
+}
+
+func (h *History) Current() (Step, bool) {
+	if h.current < 0 {
+		return Step{}, false
+	}
+	return h.steps[h.current], true
+}
+
+// Index returns the position of the current step, or -1 when empty.
+func (h *History) Index() int {
+	return h.current
+}
+
+func (h *History) Len() int {
+	return len(h.steps)
+}
+
+func (h *History) Step(i int) Step {
+	return h.steps[i]
+}
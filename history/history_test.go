@@ -0,0 +1,181 @@
+package history
+
+import (
+	"image"
+	"testing"
+)
+
+// step returns a step whose image holds side*side*4 bytes.
+func step(title string, side int) Step {
+	return Step{Title: title, Image: image.NewRGBA(image.Rect(0, 0, side, side))}
+}
+
+func titles(h *History) []string {
+	var res []string
+	for i := range h.Len() {
+		res = append(res, h.Step(i).Title)
+	}
+	return res
+}
+
+func TestPushAfterUndoDiscardsRedo(t *testing.T) {
+	h := New(0)
+	h.Reset(step("open", 1))
+	h.Push(step("a", 1))
+	h.Push(step("b", 1))
+
+	if s, ok := h.Undo(); !ok || s.Title != "a" {
+		t.Fatalf("Undo = %q, %v, want a", s.Title, ok)
+	}
+	h.Push(step("c", 1))
+
+	if got := titles(h); len(got) != 3 || got[2] != "c" {
+		t.Errorf("steps %v, want [open a c]", got)
+	}
+	if h.CanRedo() {
+		t.Error("redo still possible after pushing")
+	}
+	if h.Index() != 2 {
+		t.Errorf("Index = %d, want 2", h.Index())
+	}
+}
+
+func TestTrimKeepsCurrent(t *testing.T) {
+	// Every step takes 4x4x4 = 64 bytes.
+	h := New(150)
+	h.Reset(step("open", 4))
+	for _, title := range []string{"a", "b", "c"} {
+		h.Push(step(title, 4))
+	}
+
+	if got := titles(h); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("steps %v, want [b c]", got)
+	}
+	if s, _ := h.Current(); s.Title != "c" {
+		t.Errorf("current %q, want c", s.Title)
+	}
+
+	// A single step larger than the limit is still kept.
+	h.Push(step("huge", 16))
+	if got := titles(h); len(got) != 1 || got[0] != "huge" {
+		t.Errorf("steps %v, want [huge]", got)
+	}
+
+	// Lowering the limit after undoing drops older steps, not the current.
+	h = New(0)
+	h.Reset(step("open", 4))
+	h.Push(step("a", 4))
+	h.Push(step("b", 4))
+	h.Undo()
+	h.SetLimit(1)
+	if s, ok := h.Current(); !ok || s.Title != "a" {
+		t.Errorf("current %q, %v after SetLimit, want a", s.Title, ok)
+	}
+	if h.Index() != 0 || h.CanUndo() {
+		t.Errorf("Index = %d, CanUndo = %v, want 0, false", h.Index(), h.CanUndo())
+	}
+}
+
+func TestSizeCountsSharedImagesOnce(t *testing.T) {
+	open := step("open", 4)
+	h := New(0)
+	h.Reset(open)
+	h.Push(step("a", 4))
+	h.Push(Step{Title: "Original", Image: open.Image})
+
+	if got := h.Size(); got != 128 {
+		t.Errorf("Size = %d, want 128", got)
+	}
+
+	// A JPEG decodes to YCbCr, whose 4:2:0 chroma planes are a quarter of
+	// the luma plane each.
+	h.Push(Step{Title: "jpeg", Image: image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)})
+	if got := h.Size(); got != 128+16+4+4 {
+		t.Errorf("Size = %d, want %d", got, 128+16+4+4)
+	}
+}
+
+func TestJumpTo(t *testing.T) {
+	h := New(0)
+	h.Reset(step("open", 1))
+	h.Push(step("a", 1))
+	h.Push(step("b", 1))
+
+	for _, i := range []int{-1, 3, 100} {
+		if _, ok := h.JumpTo(i); ok {
+			t.Errorf("JumpTo(%d) succeeded", i)
+		}
+		if h.Index() != 2 {
+			t.Errorf("JumpTo(%d) moved the current step to %d", i, h.Index())
+		}
+	}
+
+	if s, ok := h.JumpTo(0); !ok || s.Title != "open" {
+		t.Errorf("JumpTo(0) = %q, %v, want open", s.Title, ok)
+	}
+	if h.Len() != 3 || !h.CanRedo() {
+		t.Errorf("JumpTo discarded steps: %v", titles(h))
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	h := New(0)
+	if _, ok := h.Current(); ok {
+		t.Error("empty history has a current step")
+	}
+	if h.CanUndo() || h.CanRedo() {
+		t.Error("empty history can undo or redo")
+	}
+	if _, ok := h.JumpTo(0); ok {
+		t.Error("JumpTo(0) succeeded on an empty history")
+	}
+}
+
+func TestFilterSteps(t *testing.T) {
+	open := Step{Title: "Open", Base: true}
+	gamma := Step{Title: "Gamma", Filter: "gamma"}
+	kernel := Step{Title: "Custom kernel"}
+	curves := Step{Title: "Curves", Filter: "curves"}
+
+	h := New(0)
+	h.Reset(open)
+	if _, err := h.FilterSteps(); err == nil {
+		t.Error("FilterSteps succeeded without any filter")
+	}
+
+	// A step without a filter in the chain must not be skipped silently.
+	h.Push(gamma)
+	h.Push(kernel)
+	h.Push(curves)
+	if _, err := h.FilterSteps(); err == nil {
+		t.Error("FilterSteps succeeded across a custom kernel")
+	}
+
+	// Going back to the original starts a new chain.
+	h.Push(Step{Title: "Original", Base: true})
+	h.Push(gamma)
+	h.Push(curves)
+	steps, err := h.FilterSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Filter != "gamma" || steps[1].Filter != "curves" {
+		t.Errorf("steps %v, want gamma and curves", steps)
+	}
+
+	// Undone steps are not part of the chain.
+	h.Undo()
+	if steps, _ := h.FilterSteps(); len(steps) != 1 {
+		t.Errorf("%d steps after undo, want 1", len(steps))
+	}
+}
+
+func TestFilterStepsTrimmedBase(t *testing.T) {
+	h := New(100)
+	h.Reset(Step{Title: "Open", Image: image.NewRGBA(image.Rect(0, 0, 4, 4)), Base: true})
+	h.Push(Step{Title: "Gamma", Filter: "gamma", Image: image.NewRGBA(image.Rect(0, 0, 4, 4))})
+
+	if _, err := h.FilterSteps(); err == nil {
+		t.Error("FilterSteps succeeded after the opened image was trimmed")
+	}
+}
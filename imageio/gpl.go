@@ -0,0 +1,33 @@
+package imageio
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// gplColumns is how many swatches per row GIMP shows for written palettes.
+const gplColumns = 8
+
+// WriteGPL writes palette as a GIMP .gpl palette file named name. Colours
+// are written without alpha, each annotated with its hex code; fully
+// transparent entries are left out.
+func WriteGPL(w io.Writer, name string, palette color.Palette) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "GIMP Palette")
+	fmt.Fprintf(bw, "Name: %s\n", strings.ReplaceAll(name, "\n", " "))
+	fmt.Fprintf(bw, "Columns: %d\n", gplColumns)
+	fmt.Fprintln(bw, "#")
+	for _, c := range palette {
+		nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if nrgba.A == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "%3d %3d %3d\t#%02x%02x%02x\n", nrgba.R, nrgba.G, nrgba.B, nrgba.R, nrgba.G, nrgba.B)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,30 @@
+package imageio
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestWriteGPL(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{},
+		color.NRGBA{0, 128, 255, 255},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGPL(&buf, "sunset\nwarm", palette); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "GIMP Palette\n" +
+		"Name: sunset warm\n" +
+		"Columns: 8\n" +
+		"#\n" +
+		"255   0   0\t#ff0000\n" +
+		"  0 128 255\t#0080ff\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+// Package imageio loads and saves images, picking the codec from the file
+// extension.
+package imageio
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+)
+
+type Format string
+
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+	GIF  Format = "gif"
+	BMP  Format = "bmp"
+)
+
+// Options tune the encoders; zero values select the codec defaults.
+type Options struct {
+	// Quality is the JPEG quality in the range 1-100.
+	Quality int
+}
+
+// FormatFromPath maps the extension of path to an output format.
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return PNG, nil
+	case ".jpg", ".jpeg":
+		return JPEG, nil
+	case ".gif":
+		return GIF, nil
+	case ".bmp":
+		return BMP, nil
+	}
+	return "", fmt.Errorf("imageio: unsupported file extension %q", filepath.Ext(path))
+}
+
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("imageio: decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Encode writes img in format. A *image.Paletted is stored as an indexed
+// PNG or GIF with its own palette.
+func Encode(w io.Writer, img image.Image, format Format, opts Options) error {
+	switch format {
+	case PNG:
+		return png.Encode(w, img)
+	case JPEG:
+		quality := opts.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		if quality < 1 || quality > 100 {
+			return fmt.Errorf("imageio: jpeg quality %d out of range [1, 100]", quality)
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case GIF:
+		return gif.Encode(w, img, nil)
+	case BMP:
+		return bmp.Encode(w, img)
+	}
+	return fmt.Errorf("imageio: unsupported format %q", format)
+}
+
+// Save encodes img into path; a partially written file is removed on error.
+func Save(path string, img image.Image, opts Options) error {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = Encode(file, img, format, opts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("imageio: encode %s: %w", path, err)
+	}
+	return nil
+}
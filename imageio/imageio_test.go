@@ -0,0 +1,168 @@
+package imageio
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"photoshop/filters"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want Format
+	}{
+		{"a.png", PNG},
+		{"dir.v2/a.JPG", JPEG},
+		{"a.jpeg", JPEG},
+		{"a.gif", GIF},
+		{"a.Bmp", BMP},
+	}
+	for _, tc := range tests {
+		got, err := FormatFromPath(tc.path)
+		if err != nil || got != tc.want {
+			t.Errorf("FormatFromPath(%q) = %q, %v, want %q", tc.path, got, err, tc.want)
+		}
+	}
+
+	for _, path := range []string{"a.tif", "a", "a.png.bak"} {
+		if _, err := FormatFromPath(path); err == nil {
+			t.Errorf("FormatFromPath(%q) succeeded", path)
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := range 8 {
+		for x := range 16 {
+			src.SetRGBA(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 200, 255})
+		}
+	}
+
+	// tolerance absorbs the lossy codecs: JPEG compression and the fixed
+	// palette GIF falls back to for RGBA images.
+	tests := []struct {
+		name      string
+		tolerance int
+	}{
+		{"out.png", 0},
+		{"out.bmp", 0},
+		{"out.jpg", 24},
+		{"out.gif", 64},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range tests {
+		path := filepath.Join(dir, tc.name)
+		if err := Save(path, src, Options{Quality: 95}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Bounds() != src.Bounds() {
+			t.Errorf("%s: bounds %v, want %v", tc.name, got.Bounds(), src.Bounds())
+			continue
+		}
+
+		diff := 0
+		for y := range 8 {
+			for x := range 16 {
+				r1, g1, b1, _ := got.At(x, y).RGBA()
+				r2, g2, b2, _ := src.At(x, y).RGBA()
+				for _, d := range []int{int(r1>>8) - int(r2>>8), int(g1>>8) - int(g2>>8), int(b1>>8) - int(b2>>8)} {
+					diff = max(diff, d, -d)
+				}
+			}
+		}
+		if diff > tc.tolerance {
+			t.Errorf("%s: channels differ by up to %d, want at most %d", tc.name, diff, tc.tolerance)
+		}
+	}
+}
+
+func TestSaveUnknownExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.tif")
+	if err := Save(path, image.NewRGBA(image.Rect(0, 0, 2, 2)), Options{}); err == nil {
+		t.Fatal("saving a .tif succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save left %s behind", path)
+	}
+}
+
+func TestEncodeJPEGQuality(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for _, quality := range []int{-1, 101} {
+		if err := Encode(&bytes.Buffer{}, img, JPEG, Options{Quality: quality}); err == nil {
+			t.Errorf("quality %d accepted", quality)
+		}
+	}
+}
+
+func TestEncodePalettedRoundTrip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := range 16 {
+		for x := range 16 {
+			if x < 2 {
+				continue // a transparent strip adds the transparent entry
+			}
+			src.SetRGBA(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 96, 255})
+		}
+	}
+
+	paletted, err := filters.ReducePalette(context.Background(), src, filters.PaletteParams{Colors: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transparent := false
+	for _, c := range paletted.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparent = true
+		}
+	}
+	if !transparent {
+		t.Fatalf("palette %v has no transparent entry", paletted.Palette)
+	}
+
+	for _, format := range []Format{PNG, GIF} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, paletted, format, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, ok := decoded.(*image.Paletted)
+		if !ok {
+			t.Errorf("%s: decoded as %T, want *image.Paletted", format, decoded)
+			continue
+		}
+		// GIF colour tables hold a power of two entries, so the palette
+		// comes back padded.
+		if len(got.Palette) != len(paletted.Palette) && (format != GIF || len(got.Palette) != 16) {
+			t.Errorf("%s: %d colours, want %d", format, len(got.Palette), len(paletted.Palette))
+			continue
+		}
+		for i := range paletted.Palette {
+			r1, g1, b1, a1 := got.Palette[i].RGBA()
+			r2, g2, b2, a2 := paletted.Palette[i].RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Errorf("%s: colour %d is %v, want %v", format, i, got.Palette[i], paletted.Palette[i])
+			}
+		}
+		if !bytes.Equal(got.Pix, paletted.Pix) {
+			t.Errorf("%s: pixel indices differ", format)
+		}
+	}
+}
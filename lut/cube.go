@@ -0,0 +1,256 @@
+package lut
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxCube1DSize = 65536
+	maxCube3DSize = 256
+	// BakeSize is the cube size LUTs that are neither 1D nor 3D tables,
+	// such as chains, are sampled at when written.
+	BakeSize = 33
+	// Bake1DSize is the size per-channel chains are sampled at.
+	Bake1DSize = 1024
+)
+
+// Cube is the content of a .cube file.
+type Cube struct {
+	Title string
+	// LUT is a *LUT1D, a *LUT3D, or a Chain of a 1D shaper followed by a
+	// cube when the file holds both.
+	LUT LUT
+}
+
+// cubeHeader collects the keywords that precede the table data.
+type cubeHeader struct {
+	title                string
+	size1D, size3D       int
+	domainMin, domainMax [3]float64
+	range1D, range3D     [2]float64
+	hasDomain            bool
+	hasRange1D           bool
+	hasRange3D           bool
+}
+
+func parseFloats(fields []string, n int) ([]float64, error) {
+	if len(fields) != n {
+		return nil, fmt.Errorf("want %d numbers, got %d", n, len(fields))
+	}
+	values := make([]float64, n)
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func parseSize(fields []string, limit int) (int, error) {
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("want one size, got %d", len(fields))
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, err
+	}
+	if size < 2 || size > limit {
+		return 0, fmt.Errorf("size %d out of range [2, %d]", size, limit)
+	}
+	return size, nil
+}
+
+// keyword applies one header line and reports whether it was one.
+func (h *cubeHeader) keyword(key string, fields []string) (bool, error) {
+	var err error
+	switch key {
+	case "TITLE":
+		title := strings.Join(fields, " ")
+		unquoted, uerr := strconv.Unquote(title)
+		if uerr != nil {
+			unquoted = strings.Trim(title, `"`)
+		}
+		h.title = unquoted
+	case "LUT_1D_SIZE":
+		h.size1D, err = parseSize(fields, maxCube1DSize)
+	case "LUT_3D_SIZE":
+		h.size3D, err = parseSize(fields, maxCube3DSize)
+	case "DOMAIN_MIN", "DOMAIN_MAX":
+		var values []float64
+		if values, err = parseFloats(fields, 3); err == nil {
+			target := &h.domainMin
+			if key == "DOMAIN_MAX" {
+				target = &h.domainMax
+			}
+			copy(target[:], values)
+			h.hasDomain = true
+		}
+	case "LUT_1D_INPUT_RANGE", "LUT_3D_INPUT_RANGE":
+		var values []float64
+		if values, err = parseFloats(fields, 2); err == nil {
+			if key == "LUT_1D_INPUT_RANGE" {
+				h.range1D, h.hasRange1D = [2]float64{values[0], values[1]}, true
+			} else {
+				h.range3D, h.hasRange3D = [2]float64{values[0], values[1]}, true
+			}
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// domain returns the input domain of the 1D or 3D table: its own input
+// range if given, else DOMAIN_MIN/MAX, else [0, 1].
+func (h *cubeHeader) domain(hasRange bool, inputRange [2]float64) ([3]float64, [3]float64) {
+	switch {
+	case hasRange:
+		lo, hi := inputRange[0], inputRange[1]
+		return [3]float64{lo, lo, lo}, [3]float64{hi, hi, hi}
+	case h.hasDomain:
+		return h.domainMin, h.domainMax
+	}
+	return unitMin, unitMax
+}
+
+// ReadCube parses a .cube file as written by Adobe and DaVinci Resolve
+// tools. With both LUT_1D_SIZE and LUT_3D_SIZE the 1D data comes first
+// and is applied before the cube.
+func ReadCube(r io.Reader) (*Cube, error) {
+	var header cubeHeader
+	var data [][3]float64
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		if len(data) == 0 {
+			isKeyword, err := header.keyword(fields[0], fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("lut: line %d: %s: %w", lineNo, fields[0], err)
+			}
+			if isKeyword {
+				continue
+			}
+		}
+
+		values, err := parseFloats(fields, 3)
+		if err != nil {
+			return nil, fmt.Errorf("lut: line %d: %w", lineNo, err)
+		}
+		data = append(data, [3]float64{values[0], values[1], values[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("lut: %w", err)
+	}
+
+	if header.size1D == 0 && header.size3D == 0 {
+		return nil, fmt.Errorf("lut: no LUT_1D_SIZE or LUT_3D_SIZE")
+	}
+	want := header.size1D + header.size3D*header.size3D*header.size3D
+	if len(data) != want {
+		return nil, fmt.Errorf("lut: %d table entries, want %d", len(data), want)
+	}
+
+	var chain Chain
+	if header.size1D > 0 {
+		table := &LUT1D{}
+		table.DomainMin, table.DomainMax = header.domain(header.hasRange1D, header.range1D)
+		for c := range table.Tables {
+			table.Tables[c] = make([]float64, header.size1D)
+			for i, entry := range data[:header.size1D] {
+				table.Tables[c][i] = entry[c]
+			}
+		}
+		if err := table.Validate(); err != nil {
+			return nil, err
+		}
+		chain = append(chain, table)
+	}
+	if header.size3D > 0 {
+		cube := &LUT3D{Size: header.size3D, Data: data[header.size1D:], Interpolation: Tetrahedral}
+		cube.DomainMin, cube.DomainMax = header.domain(header.hasRange3D, header.range3D)
+		if header.size1D > 0 && !header.hasRange3D {
+			// The shaper already maps the domain onto the cube's input.
+			cube.DomainMin, cube.DomainMax = unitMin, unitMax
+		}
+		if err := cube.Validate(); err != nil {
+			return nil, err
+		}
+		chain = append(chain, cube)
+	}
+
+	if len(chain) == 1 {
+		return &Cube{Title: header.title, LUT: chain[0]}, nil
+	}
+	return &Cube{Title: header.title, LUT: chain}, nil
+}
+
+func writeDomain(w io.Writer, lo, hi [3]float64) {
+	if lo == unitMin && hi == unitMax {
+		return
+	}
+	fmt.Fprintf(w, "DOMAIN_MIN %s %s %s\n", formatValue(lo[0]), formatValue(lo[1]), formatValue(lo[2]))
+	fmt.Fprintf(w, "DOMAIN_MAX %s %s %s\n", formatValue(hi[0]), formatValue(hi[1]), formatValue(hi[2]))
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// WriteCube writes cube.LUT as a .cube file. 1D and 3D tables are written
+// as they are; chains are baked into a Bake1DSize table when they keep the
+// channels apart and into a BakeSize cube otherwise.
+func WriteCube(w io.Writer, cube *Cube) error {
+	bw := bufio.NewWriter(w)
+
+	if cube.Title != "" {
+		fmt.Fprintf(bw, "TITLE %s\n", strconv.Quote(cube.Title))
+	}
+
+	l := cube.LUT
+	if _, isTable := l.(*LUT1D); !isTable && perChannel(l) {
+		baked, err := Bake1D(l, Bake1DSize)
+		if err != nil {
+			return err
+		}
+		l = baked
+	}
+
+	switch l := l.(type) {
+	case *LUT1D:
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "LUT_1D_SIZE %d\n", l.Size())
+		writeDomain(bw, l.DomainMin, l.DomainMax)
+		for i := range l.Size() {
+			fmt.Fprintf(bw, "%s %s %s\n", formatValue(l.Tables[0][i]), formatValue(l.Tables[1][i]), formatValue(l.Tables[2][i]))
+		}
+	default:
+		l3, ok := l.(*LUT3D)
+		if !ok {
+			l3 = Bake3D(l, BakeSize, Tetrahedral)
+		}
+		if err := l3.Validate(); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "LUT_3D_SIZE %d\n", l3.Size)
+		writeDomain(bw, l3.DomainMin, l3.DomainMax)
+		for _, entry := range l3.Data {
+			fmt.Fprintf(bw, "%s %s %s\n", formatValue(entry[0]), formatValue(entry[1]), formatValue(entry[2]))
+		}
+	}
+
+	return bw.Flush()
+}
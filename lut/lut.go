@@ -0,0 +1,294 @@
+// Package lut implements colour lookup tables: per-channel 1D tables, 3D
+// cubes with trilinear or tetrahedral interpolation, chains of both and the
+// .cube file format of Adobe and DaVinci Resolve.
+package lut
+
+import (
+	"fmt"
+	"math"
+)
+
+// LUT maps an RGB colour with components nominally in [0, 1] to another.
+type LUT interface {
+	Apply(r, g, b float64) (float64, float64, float64)
+}
+
+var (
+	unitMin = [3]float64{0, 0, 0}
+	unitMax = [3]float64{1, 1, 1}
+)
+
+// gridPosition maps v from [lo, hi] onto the grid 0..size-1, clamping
+// values outside the domain.
+func gridPosition(v, lo, hi float64, size int) float64 {
+	t := (v - lo) / (hi - lo)
+	return min(max(t, 0), 1) * float64(size-1)
+}
+
+// split returns the grid cell below pos and the fraction towards the next.
+func split(pos float64, size int) (int, int, float64) {
+	i := int(pos)
+	if i >= size-1 {
+		return size - 1, size - 1, 0
+	}
+	return i, i + 1, pos - float64(i)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// LUT1D maps every channel through its own curve, sampled at Size evenly
+// spaced inputs from DomainMin to DomainMax and interpolated linearly.
+type LUT1D struct {
+	Tables               [3][]float64
+	DomainMin, DomainMax [3]float64
+}
+
+// NewLUT1D returns the identity table with size entries per channel.
+func NewLUT1D(size int) *LUT1D {
+	l := &LUT1D{DomainMin: unitMin, DomainMax: unitMax}
+	for c := range l.Tables {
+		l.Tables[c] = make([]float64, size)
+		for i := range size {
+			l.Tables[c][i] = float64(i) / float64(size-1)
+		}
+	}
+	return l
+}
+
+// FromTable wraps an 8-bit table shared by R, G and B.
+func FromTable(table *[256]uint8) *LUT1D {
+	return FromTables(&[3][256]uint8{*table, *table, *table})
+}
+
+// FromTables wraps 8-bit tables, one per channel.
+func FromTables(tables *[3][256]uint8) *LUT1D {
+	l := &LUT1D{DomainMin: unitMin, DomainMax: unitMax}
+	for c := range l.Tables {
+		l.Tables[c] = make([]float64, 256)
+		for i, v := range tables[c] {
+			l.Tables[c][i] = float64(v) / 255
+		}
+	}
+	return l
+}
+
+func (l *LUT1D) Size() int {
+	return len(l.Tables[0])
+}
+
+func (l *LUT1D) Validate() error {
+	size := l.Size()
+	if size < 2 {
+		return fmt.Errorf("lut: 1D table size %d must be at least 2", size)
+	}
+	for c, table := range l.Tables {
+		if len(table) != size {
+			return fmt.Errorf("lut: 1D channel %d has %d entries, want %d", c, len(table), size)
+		}
+	}
+	return validateDomain(l.DomainMin, l.DomainMax)
+}
+
+func validateDomain(lo, hi [3]float64) error {
+	for c := range lo {
+		if !(lo[c] < hi[c]) {
+			return fmt.Errorf("lut: domain [%g, %g] of channel %d is empty", lo[c], hi[c], c)
+		}
+	}
+	return nil
+}
+
+func (l *LUT1D) Apply(r, g, b float64) (float64, float64, float64) {
+	var out [3]float64
+	size := l.Size()
+	for c, v := range [3]float64{r, g, b} {
+		i0, i1, t := split(gridPosition(v, l.DomainMin[c], l.DomainMax[c], size), size)
+		out[c] = lerp(l.Tables[c][i0], l.Tables[c][i1], t)
+	}
+	return out[0], out[1], out[2]
+}
+
+type Interpolation int
+
+const (
+	Trilinear Interpolation = iota
+	// Tetrahedral splits every cell into six tetrahedra; it keeps the gray
+	// axis exact and is what most grading tools use.
+	Tetrahedral
+)
+
+var interpolationNames = []string{"Trilinear", "Tetrahedral"}
+
+// InterpolationNames lists the interpolations in the order of their values.
+func InterpolationNames() []string {
+	return append([]string(nil), interpolationNames...)
+}
+
+func (i Interpolation) String() string {
+	if i < 0 || int(i) >= len(interpolationNames) {
+		return fmt.Sprintf("Interpolation(%d)", int(i))
+	}
+	return interpolationNames[i]
+}
+
+// LUT3D maps colours through a Size x Size x Size lattice spanning
+// DomainMin to DomainMax.
+type LUT3D struct {
+	Size int
+	// Data holds the output of every lattice point, red changing fastest,
+	// then green, then blue, as in .cube files.
+	Data                 [][3]float64
+	DomainMin, DomainMax [3]float64
+	Interpolation        Interpolation
+}
+
+// NewLUT3D returns the identity cube of the given size.
+func NewLUT3D(size int) *LUT3D {
+	l := &LUT3D{Size: size, Data: make([][3]float64, size*size*size), DomainMin: unitMin, DomainMax: unitMax}
+	step := 1 / float64(size-1)
+	for b := range size {
+		for g := range size {
+			for r := range size {
+				l.Data[l.index(r, g, b)] = [3]float64{float64(r) * step, float64(g) * step, float64(b) * step}
+			}
+		}
+	}
+	return l
+}
+
+func (l *LUT3D) Validate() error {
+	if l.Size < 2 {
+		return fmt.Errorf("lut: 3D table size %d must be at least 2", l.Size)
+	}
+	if len(l.Data) != l.Size*l.Size*l.Size {
+		return fmt.Errorf("lut: 3D table of size %d has %d entries, want %d", l.Size, len(l.Data), l.Size*l.Size*l.Size)
+	}
+	if l.Interpolation != Trilinear && l.Interpolation != Tetrahedral {
+		return fmt.Errorf("lut: unknown interpolation %d", l.Interpolation)
+	}
+	return validateDomain(l.DomainMin, l.DomainMax)
+}
+
+func (l *LUT3D) index(r, g, b int) int {
+	return (b*l.Size+g)*l.Size + r
+}
+
+func (l *LUT3D) Apply(r, g, b float64) (float64, float64, float64) {
+	r0, r1, fr := split(gridPosition(r, l.DomainMin[0], l.DomainMax[0], l.Size), l.Size)
+	g0, g1, fg := split(gridPosition(g, l.DomainMin[1], l.DomainMax[1], l.Size), l.Size)
+	b0, b1, fb := split(gridPosition(b, l.DomainMin[2], l.DomainMax[2], l.Size), l.Size)
+
+	// cRGB is the lattice point at the low (0) or high (1) corner per axis.
+	c000 := l.Data[l.index(r0, g0, b0)]
+	c100 := l.Data[l.index(r1, g0, b0)]
+	c010 := l.Data[l.index(r0, g1, b0)]
+	c110 := l.Data[l.index(r1, g1, b0)]
+	c001 := l.Data[l.index(r0, g0, b1)]
+	c101 := l.Data[l.index(r1, g0, b1)]
+	c011 := l.Data[l.index(r0, g1, b1)]
+	c111 := l.Data[l.index(r1, g1, b1)]
+
+	var out [3]float64
+	for c := range out {
+		if l.Interpolation == Tetrahedral {
+			out[c] = tetrahedral(fr, fg, fb, c000[c], c100[c], c010[c], c110[c], c001[c], c101[c], c011[c], c111[c])
+			continue
+		}
+		low := lerp(lerp(c000[c], c100[c], fr), lerp(c010[c], c110[c], fr), fg)
+		high := lerp(lerp(c001[c], c101[c], fr), lerp(c011[c], c111[c], fr), fg)
+		out[c] = lerp(low, high, fb)
+	}
+	return out[0], out[1], out[2]
+}
+
+// tetrahedral interpolates inside the tetrahedron of the cell that
+// contains (fr, fg, fb), picked by the order of the fractions.
+func tetrahedral(fr, fg, fb, c000, c100, c010, c110, c001, c101, c011, c111 float64) float64 {
+	switch {
+	case fr > fg && fg > fb:
+		return c000 + fr*(c100-c000) + fg*(c110-c100) + fb*(c111-c110)
+	case fr > fb && fb >= fg:
+		return c000 + fr*(c100-c000) + fb*(c101-c100) + fg*(c111-c101)
+	case fb >= fr && fr > fg:
+		return c000 + fb*(c001-c000) + fr*(c101-c001) + fg*(c111-c101)
+	case fb > fg && fg >= fr:
+		return c000 + fb*(c001-c000) + fg*(c011-c001) + fr*(c111-c011)
+	case fg >= fb && fb > fr:
+		return c000 + fg*(c010-c000) + fb*(c011-c010) + fr*(c111-c011)
+	default:
+		return c000 + fg*(c010-c000) + fr*(c110-c010) + fb*(c111-c110)
+	}
+}
+
+// Chain applies its LUTs one after the other.
+type Chain []LUT
+
+func (c Chain) Apply(r, g, b float64) (float64, float64, float64) {
+	for _, l := range c {
+		r, g, b = l.Apply(r, g, b)
+	}
+	return r, g, b
+}
+
+// perChannel reports whether l treats the channels independently, i.e.
+// is a 1D table or a chain of them.
+func perChannel(l LUT) bool {
+	switch l := l.(type) {
+	case *LUT1D:
+		return true
+	case Chain:
+		for _, inner := range l {
+			if !perChannel(inner) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func toByte(v float64) uint8 {
+	return uint8(math.Round(min(max(v, 0), 1) * 255))
+}
+
+// Tables8 compiles a per-channel LUT into 8-bit tables; ok is false for
+// LUTs that mix the channels.
+func Tables8(l LUT) (tables *[3][256]uint8, ok bool) {
+	if !perChannel(l) {
+		return nil, false
+	}
+
+	tables = new([3][256]uint8)
+	for level := range 256 {
+		v := float64(level) / 255
+		r, g, b := l.Apply(v, v, v)
+		tables[0][level], tables[1][level], tables[2][level] = toByte(r), toByte(g), toByte(b)
+	}
+	return tables, true
+}
+
+// Bake1D samples a per-channel LUT into a single table of the given size.
+func Bake1D(l LUT, size int) (*LUT1D, error) {
+	if !perChannel(l) {
+		return nil, fmt.Errorf("lut: cannot bake a LUT mixing the channels into a 1D table")
+	}
+	baked := NewLUT1D(size)
+	for i := range size {
+		v := float64(i) / float64(size-1)
+		baked.Tables[0][i], baked.Tables[1][i], baked.Tables[2][i] = l.Apply(v, v, v)
+	}
+	return baked, nil
+}
+
+// Bake3D samples any LUT, e.g. a chain, into a single cube.
+func Bake3D(l LUT, size int, interpolation Interpolation) *LUT3D {
+	baked := NewLUT3D(size)
+	baked.Interpolation = interpolation
+	for i, in := range baked.Data {
+		r, g, b := l.Apply(in[0], in[1], in[2])
+		baked.Data[i] = [3]float64{r, g, b}
+	}
+	return baked
+}
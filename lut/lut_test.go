@@ -0,0 +1,154 @@
+package lut
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func close3(r, g, b float64, want [3]float64) bool {
+	return math.Abs(r-want[0]) < 1e-9 && math.Abs(g-want[1]) < 1e-9 && math.Abs(b-want[2]) < 1e-9
+}
+
+// affineCube fills a cube with an affine colour transform, which both
+// interpolations must reproduce exactly between the lattice points.
+func affineCube(size int, interpolation Interpolation) *LUT3D {
+	cube := NewLUT3D(size)
+	cube.Interpolation = interpolation
+	for i, in := range cube.Data {
+		cube.Data[i] = affine(in[0], in[1], in[2])
+	}
+	return cube
+}
+
+func affine(r, g, b float64) [3]float64 {
+	return [3]float64{0.5*r + 0.25*g + 0.1, 0.2*g - 0.3*b + 0.4, 0.6*b + 0.1*r}
+}
+
+func TestLUT3DInterpolation(t *testing.T) {
+	samples := [][3]float64{{0, 0, 0}, {1, 1, 1}, {0.3, 0.7, 0.1}, {0.9, 0.2, 0.55}, {0.5, 0.5, 0.5}, {0.12, 0.12, 0.8}}
+
+	for _, interpolation := range []Interpolation{Trilinear, Tetrahedral} {
+		identity := NewLUT3D(5)
+		identity.Interpolation = interpolation
+		cube := affineCube(5, interpolation)
+
+		for _, s := range samples {
+			if r, g, b := identity.Apply(s[0], s[1], s[2]); !close3(r, g, b, s) {
+				t.Errorf("%v identity(%v) = %v, %v, %v", interpolation, s, r, g, b)
+			}
+			if r, g, b := cube.Apply(s[0], s[1], s[2]); !close3(r, g, b, affine(s[0], s[1], s[2])) {
+				t.Errorf("%v affine(%v) = %v, %v, %v, want %v", interpolation, s, r, g, b, affine(s[0], s[1], s[2]))
+			}
+		}
+	}
+}
+
+func TestLUT3DClampsOutsideDomain(t *testing.T) {
+	cube := NewLUT3D(3)
+	if r, g, b := cube.Apply(-0.5, 1.5, 0.5); !close3(r, g, b, [3]float64{0, 1, 0.5}) {
+		t.Errorf("Apply(-0.5, 1.5, 0.5) = %v, %v, %v", r, g, b)
+	}
+}
+
+func TestTables8(t *testing.T) {
+	var table [256]uint8
+	for level := range table {
+		table[level] = uint8(255 - level)
+	}
+
+	tables, ok := Tables8(Chain{FromTable(&table), NewLUT1D(2)})
+	if !ok {
+		t.Fatal("a chain of 1D tables is not per channel")
+	}
+	for c := range tables {
+		if tables[c] != table {
+			t.Fatalf("channel %d = %v, want %v", c, tables[c], table)
+		}
+	}
+
+	if _, ok := Tables8(Chain{NewLUT1D(2), NewLUT3D(2)}); ok {
+		t.Error("Tables8 compiled a chain containing a 3D LUT")
+	}
+}
+
+const sampleCube = `# Created by hand
+TITLE "Swap red and blue"
+
+LUT_3D_SIZE 2
+DOMAIN_MIN 0.0 0.0 0.0
+DOMAIN_MAX 1.0 1.0 1.0
+0 0 0
+0 0 1
+0 1 0
+0 1 1
+1 0 0
+1 0 1
+1 1 0
+1 1 1
+`
+
+func TestReadCube(t *testing.T) {
+	cube, err := ReadCube(strings.NewReader(sampleCube))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cube.Title != "Swap red and blue" {
+		t.Errorf("title %q", cube.Title)
+	}
+	if r, g, b := cube.LUT.Apply(0.2, 0.4, 0.9); !close3(r, g, b, [3]float64{0.9, 0.4, 0.2}) {
+		t.Errorf("Apply(0.2, 0.4, 0.9) = %v, %v, %v", r, g, b)
+	}
+}
+
+func TestReadCubeErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"no size":       "0 0 0\n1 1 1\n",
+		"short table":   "LUT_3D_SIZE 2\n0 0 0\n",
+		"bad number":    "LUT_1D_SIZE 2\n0 0 0\n1 x 1\n",
+		"tiny size":     "LUT_1D_SIZE 1\n0 0 0\n",
+		"empty domain":  "LUT_1D_SIZE 2\nDOMAIN_MIN 1 0 0\nDOMAIN_MAX 1 1 1\n0 0 0\n1 1 1\n",
+		"two per entry": "LUT_1D_SIZE 2\n0 0\n1 1\n",
+	} {
+		if _, err := ReadCube(strings.NewReader(text)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestCubeRoundTrip(t *testing.T) {
+	shaper := NewLUT1D(4)
+	for c := range shaper.Tables {
+		for i := range shaper.Tables[c] {
+			shaper.Tables[c][i] = math.Sqrt(shaper.Tables[c][i])
+		}
+	}
+
+	for name, l := range map[string]LUT{
+		"1D":    shaper,
+		"3D":    affineCube(4, Tetrahedral),
+		"chain": Chain{shaper, affineCube(4, Tetrahedral)},
+	} {
+		var buf bytes.Buffer
+		if err := WriteCube(&buf, &Cube{Title: name, LUT: l}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		read, err := ReadCube(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if read.Title != name {
+			t.Errorf("%s: title %q", name, read.Title)
+		}
+
+		for _, s := range [][3]float64{{0, 0, 0}, {1, 1, 1}, {0.25, 0.5, 0.75}} {
+			wantR, wantG, wantB := l.Apply(s[0], s[1], s[2])
+			r, g, b := read.LUT.Apply(s[0], s[1], s[2])
+			// Baking the chain into a cube samples it; values stay close.
+			if math.Abs(r-wantR) > 0.02 || math.Abs(g-wantG) > 0.02 || math.Abs(b-wantB) > 0.02 {
+				t.Errorf("%s: Apply(%v) = %v, %v, %v after round trip, want %v, %v, %v", name, s, r, g, b, wantR, wantG, wantB)
+			}
+		}
+	}
+}